@@ -0,0 +1,397 @@
+package client
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/qxoo/mywallet/db"
+	"github.com/qxoo/mywallet/mytoken"
+	"github.com/qxoo/mywallet/units"
+	"github.com/qxoo/mywallet/wallet"
+)
+
+const BATCH_FAILED = "failed"
+
+var batchHeader = []string{"row", "to", "amount", "token", "hash", "status", "error"}
+
+type payout struct {
+	row    *BatchRow
+	to     string
+	amount *big.Int
+	token  db.Token
+}
+
+func (p payout) sent() bool {
+	return p.row.Hash != "" && p.row.Status != BATCH_FAILED
+}
+
+// readPayouts reads rows of to, amount and an optional token, a first row
+// starting with "to" is a header. Row is the line in the file.
+func readPayouts(file string) ([]*BatchRow, error) {
+	fl, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("Read File error: %w", err)
+	}
+	defer fl.Close()
+
+	reader := csv.NewReader(fl)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	rows := []*BatchRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Read File error: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "to") {
+			continue
+		}
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("%w: row %d needs to, amount and an optional token", ErrUsage, line)
+		}
+		row := &BatchRow{Row: line, To: strings.TrimSpace(record[0]), Amount: strings.TrimSpace(record[1])}
+		if len(record) == 3 {
+			row.Token = strings.TrimSpace(record[2])
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no payouts in %s", ErrUsage, file)
+	}
+	return rows, nil
+}
+
+// readResults takes hash, status and error of rows sent by a previous run
+// from the results file, if there is one.
+func readResults(file string, rows []*BatchRow) error {
+	fl, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Read Results error: %w", err)
+	}
+	defer fl.Close()
+
+	records, err := csv.NewReader(fl).ReadAll()
+	if err != nil {
+		return fmt.Errorf("Read Results error: %w", err)
+	}
+	byRow := map[int]*BatchRow{}
+	for _, row := range rows {
+		byRow[row.Row] = row
+	}
+	for i, record := range records {
+		if i == 0 || len(record) != len(batchHeader) {
+			continue
+		}
+		n, err := strconv.Atoi(record[0])
+		if err != nil {
+			return fmt.Errorf("Read Results error: invalid row %s", record[0])
+		}
+		row, ok := byRow[n]
+		if !ok || row.To != record[1] || row.Amount != record[2] || row.Token != record[3] {
+			return fmt.Errorf("%w: %s does not match the payouts at row %d, move it away to start over", ErrUsage, file, n)
+		}
+		row.Hash, row.Status, row.Error = record[4], record[5], record[6]
+	}
+	return nil
+}
+
+func writeResults(file string, rows []*BatchRow) error {
+	tmp := file + ".tmp"
+	fl, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("Write Results error: %w", err)
+	}
+	writer := csv.NewWriter(fl)
+	writer.Write(batchHeader)
+	for _, row := range rows {
+		writer.Write([]string{strconv.Itoa(row.Row), row.To, row.Amount, row.Token, row.Hash, row.Status, row.Error})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		fl.Close()
+		return fmt.Errorf("Write Results error: %w", err)
+	}
+	if err := fl.Close(); err != nil {
+		return fmt.Errorf("Write Results error: %w", err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		return fmt.Errorf("Write Results error: %w", err)
+	}
+	return nil
+}
+
+func payoutSymbol(token db.Token) string {
+	if token.Address == "" {
+		return "ETH"
+	}
+	return tokenSymbol(token)
+}
+
+func payoutDecimals(token db.Token) int {
+	if token.Address == "" {
+		return 18
+	}
+	return int(token.Decimals)
+}
+
+// batchWait keeps the status of tx in row, waiting for it as requested.
+func (cli CmdClient) batchWait(row *BatchRow, w *wallet.Wallet, tx *types.Transaction, wait *wallet.WaitOpts) error {
+	txresult, err := cli.waitReceipt(w.Client, tx, wait)
+	row.Hash, row.Status, row.Error = txresult.Hash, txresult.Status, ""
+	if err != nil {
+		row.Error = err.Error()
+	}
+	return err
+}
+
+// Batch pays the rows of a csv file from the wallet name. Every row and the
+// totals against the balances are checked before the first send, then the
+// payouts are sent one by one with reserved nonces. Results are written as soon
+// as a row is sent and again with its final status, a run with the same results
+// file skips the rows sent already.
+func (cli CmdClient) Batch(pass string, name string, file string, results string, yes bool, wait *wallet.WaitOpts) error {
+	if file == "" {
+		return fmt.Errorf("%w: batch needs -file", ErrUsage)
+	}
+	if results == "" {
+		results = strings.TrimSuffix(file, ".csv") + ".results.csv"
+	}
+	rows, err := readPayouts(file)
+	if err != nil {
+		return err
+	}
+	if err := readResults(results, rows); err != nil {
+		return err
+	}
+
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
+	w, err := wallet.LoadWallet(cli.Path, pass, addr)
+	if err != nil {
+		return fmt.Errorf("Load Wallet error: %w", err)
+	}
+	w.Nonces = cli.nonces()
+	err = w.InitEthClient(cli.Url)
+	if err != nil {
+		return fmt.Errorf("Init EthClient error: %w", err)
+	}
+	defer w.CloseClient()
+
+	// one token wallet per token, for decimals, balance and sending
+	token_ws := map[string]*mytoken.TokenWallet{}
+	defer func() {
+		for _, tw := range token_ws {
+			tw.Close()
+		}
+	}()
+	tokens := map[string]db.Token{}
+	loadToken := func(key string) (db.Token, error) {
+		if token, ok := tokens[key]; ok {
+			return token, nil
+		}
+		token, registered, err := resolveToken(cli.Path, key)
+		if err != nil {
+			return token, err
+		}
+		tw, ok := token_ws[token.Address]
+		if !ok {
+			if tw, err = mytoken.NewTokenWallet(cli.Url, cli.Path, pass, addr, token.Address); err != nil {
+				return token, fmt.Errorf("Load Token Wallet error: %w", err)
+			}
+			tw.UseNonces(cli.nonces())
+			token_ws[token.Address] = tw
+		}
+		if err := loadTokenDecimals(tw, &token, registered); err != nil {
+			return token, err
+		}
+		tokens[key] = token
+		return token, nil
+	}
+
+	payouts := []payout{}
+	problems := []string{}
+	totals := map[string]*big.Int{}
+	assets := []db.Token{}
+	for _, row := range rows {
+		p := payout{row: row}
+		var err error
+		if p.to, err = resolveAddress(cli.Path, row.To); err != nil {
+			problems = append(problems, fmt.Sprintf("row %d: %v", row.Row, err))
+			continue
+		}
+		if row.Token == "" {
+			p.amount, err = units.ParseUnit(row.Amount, "ether")
+		} else if p.token, err = loadToken(row.Token); err == nil {
+			p.amount, err = units.ParseDecimal(row.Amount, int(p.token.Decimals))
+		}
+		if err == nil && p.amount.Sign() <= 0 {
+			err = fmt.Errorf("amount must be positive")
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("row %d: %v", row.Row, err))
+			continue
+		}
+		payouts = append(payouts, p)
+		if p.sent() {
+			continue
+		}
+		if _, ok := totals[p.token.Address]; !ok {
+			totals[p.token.Address] = big.NewInt(0)
+			assets = append(assets, p.token)
+		}
+		totals[p.token.Address].Add(totals[p.token.Address], p.amount)
+	}
+
+	cli.setResult(BatchResult{Name: name, From: addr, File: file, Results: results, Rows: rows})
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrUsage, strings.Join(problems, "; "))
+	}
+
+	// every row pays its gas in ETH, at most the max price per unit
+	fees, err := wallet.SuggestFees(w.Client, wallet.FeeOpts{})
+	if err != nil {
+		return fmt.Errorf("Suggest Fees error: %w", wallet.Classify(err))
+	}
+	gas := uint64(0)
+	for _, p := range payouts {
+		if p.sent() {
+			continue
+		}
+		if p.token.Address == "" {
+			gas += params.TxGas
+			continue
+		}
+		data, err := mytoken.TransferData(p.to, p.amount)
+		if err != nil {
+			return err
+		}
+		token_addr := common.HexToAddress(p.token.Address)
+		estimated, err := wallet.EstimateGas(w.Client, ethereum.CallMsg{From: w.Account.Address, To: &token_addr, Data: data})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("row %d: Estimate Gas error: %v", p.row.Row, wallet.Classify(err)))
+			continue
+		}
+		gas += estimated
+	}
+	maxfees := new(big.Int).Mul(new(big.Int).SetUint64(gas), fees.MaxPrice())
+	if maxfees.Sign() > 0 {
+		if _, ok := totals[""]; !ok {
+			totals[""] = big.NewInt(0)
+			assets = append(assets, db.Token{})
+		}
+		totals[""].Add(totals[""], maxfees)
+	}
+
+	cli.println("Batch: ", file, " From: ", name, addr)
+	cli.printf("Fees: up to %s ETH for %d gas, %s, included in the ETH total\n", units.FormatEther(maxfees), gas, fees)
+	cli.println()
+	cli.println("\tAsset \tTotal \tBalance")
+	cli.println("----------------------------------")
+	for _, token := range assets {
+		var balance *big.Int
+		if token.Address == "" {
+			balance, err = w.GetBalance()
+		} else {
+			balance, err = token_ws[token.Address].Balacne(addr)
+		}
+		if err != nil {
+			return fmt.Errorf("Get Balacne error: %w", err)
+		}
+		symbol, decimals := payoutSymbol(token), payoutDecimals(token)
+		total := units.Format(totals[token.Address], decimals)
+		cli.printf("\t%s \t%s \t%s\n", symbol, total, units.Format(balance, decimals))
+		if balance.Cmp(totals[token.Address]) < 0 {
+			problems = append(problems, fmt.Sprintf("total %s %s exceeds balance %s", total, symbol, units.Format(balance, decimals)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrUsage, strings.Join(problems, "; "))
+	}
+
+	todo := 0
+	for _, p := range payouts {
+		if !p.sent() {
+			todo++
+		}
+	}
+	cli.println()
+	cli.println("Payouts: ", len(payouts), " Sent already: ", len(payouts)-todo, " To send: ", todo)
+	if todo == 0 {
+		return nil
+	}
+	if !yes && !confirm(fmt.Sprintf("Send %d payouts from %s?", todo, name)) {
+		return fmt.Errorf("Batch canceled")
+	}
+
+	reverted := 0
+	for _, p := range payouts {
+		if p.sent() {
+			continue
+		}
+		cli.println()
+		cli.println("Row ", p.row.Row, ": ", p.row.Amount, payoutSymbol(p.token), " to ", p.to)
+
+		var tx *types.Transaction
+		var err error
+		record := db.Transaction{Kind: "transfer", From: addr, To: common.HexToAddress(p.to).Hex(), Value: p.amount.String()}
+		if p.token.Address == "" {
+			if tx, err = w.Transfer(p.to, p.amount, nil, wallet.FeeOpts{}); err != nil {
+				err = fmt.Errorf("Transfer error: %w", err)
+			}
+		} else {
+			tw := token_ws[p.token.Address]
+			record.Token = tw.Address().Hex()
+			if tx, err = tw.Transfer(p.to, p.amount); err != nil {
+				err = fmt.Errorf("Send Token error: %w", err)
+			}
+		}
+		if err != nil {
+			p.row.Status, p.row.Error = BATCH_FAILED, err.Error()
+			if werr := writeResults(results, rows); werr != nil {
+				return werr
+			}
+			return fmt.Errorf("Batch stopped at row %d, run again to resume: %w", p.row.Row, err)
+		}
+
+		// the hash is kept before waiting, a rerun must not pay the row again
+		cli.saveTx(tx, record)
+		p.row.Hash, p.row.Status, p.row.Error = tx.Hash().Hex(), db.TxPending, ""
+		if err := writeResults(results, rows); err != nil {
+			return err
+		}
+		if err := cli.batchWait(p.row, w, tx, wait); errors.Is(err, wallet.ErrReverted) {
+			reverted++
+		}
+		if err := writeResults(results, rows); err != nil {
+			return err
+		}
+	}
+
+	cli.println()
+	cli.println("Results written to ", results)
+	if reverted > 0 {
+		return fmt.Errorf("%w: %d payouts, see %s", wallet.ErrReverted, reverted, results)
+	}
+	return nil
+}
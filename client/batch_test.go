@@ -0,0 +1,147 @@
+package client
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestReadPayouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []BatchRow
+		fail    bool
+	}{
+		{
+			name:    "header and token",
+			content: "to,amount,token\nalice, 1.5\n0x000000000000000000000000000000000000dEaD,2, DAI \n",
+			want: []BatchRow{
+				{Row: 2, To: "alice", Amount: "1.5"},
+				{Row: 3, To: "0x000000000000000000000000000000000000dEaD", Amount: "2", Token: "DAI"},
+			},
+		},
+		{
+			name:    "comments and blank lines",
+			content: "# payroll\nbob,1\n\ncarol,0.25,USDC\n",
+			want:    []BatchRow{{Row: 2, To: "bob", Amount: "1"}, {Row: 4, To: "carol", Amount: "0.25", Token: "USDC"}},
+		},
+		{name: "header only", content: "to,amount\n", fail: true},
+		{name: "empty", content: "", fail: true},
+		{name: "missing amount", content: "bob\n", fail: true},
+		{name: "extra column", content: "bob,1,DAI,x\n", fail: true},
+	}
+	for _, tt := range tests {
+		rows, err := readPayouts(writeFile(t, "payouts.csv", tt.content))
+		if tt.fail {
+			if !errors.Is(err, ErrUsage) {
+				t.Errorf("%s: error %v, want ErrUsage", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if len(rows) != len(tt.want) {
+			t.Errorf("%s: %d rows, want %d", tt.name, len(rows), len(tt.want))
+			continue
+		}
+		for i, row := range rows {
+			if *row != tt.want[i] {
+				t.Errorf("%s: row %d = %+v, want %+v", tt.name, i, *row, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestReadResults(t *testing.T) {
+	payouts := func() []*BatchRow {
+		return []*BatchRow{
+			{Row: 1, To: "alice", Amount: "1.5"},
+			{Row: 2, To: "bob", Amount: "2", Token: "DAI"},
+		}
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    []BatchRow
+		fail    bool
+	}{
+		{
+			name: "resume",
+			content: "row,to,amount,token,hash,status,error\n" +
+				"1,alice,1.5,,0xaa,success,\n" +
+				"2,bob,2,DAI,,failed,Transfer error: nonce too low\n",
+			want: []BatchRow{
+				{Row: 1, To: "alice", Amount: "1.5", Hash: "0xaa", Status: "success"},
+				{Row: 2, To: "bob", Amount: "2", Token: "DAI", Status: "failed", Error: "Transfer error: nonce too low"},
+			},
+		},
+		{
+			name:    "partial",
+			content: "row,to,amount,token,hash,status,error\n1,alice,1.5,,0xaa,pending,\n",
+			want: []BatchRow{
+				{Row: 1, To: "alice", Amount: "1.5", Hash: "0xaa", Status: "pending"},
+				{Row: 2, To: "bob", Amount: "2", Token: "DAI"},
+			},
+		},
+		{name: "changed amount", content: "row,to,amount,token,hash,status,error\n1,alice,1.6,,0xaa,success,\n", fail: true},
+		{name: "changed token", content: "row,to,amount,token,hash,status,error\n2,bob,2,USDC,0xbb,success,\n", fail: true},
+		{name: "unknown row", content: "row,to,amount,token,hash,status,error\n3,carol,1,,0xcc,success,\n", fail: true},
+	}
+	for _, tt := range tests {
+		rows := payouts()
+		err := readResults(writeFile(t, "results.csv", tt.content), rows)
+		if tt.fail {
+			if !errors.Is(err, ErrUsage) {
+				t.Errorf("%s: error %v, want ErrUsage", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		for i, row := range rows {
+			if *row != tt.want[i] {
+				t.Errorf("%s: row %d = %+v, want %+v", tt.name, i, *row, tt.want[i])
+			}
+		}
+	}
+
+	rows := payouts()
+	if err := readResults(filepath.Join(t.TempDir(), "missing.csv"), rows); err != nil {
+		t.Errorf("missing results file: unexpected error: %v", err)
+	}
+}
+
+func TestWriteResultsRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "results.csv")
+	sent := []*BatchRow{
+		{Row: 1, To: "alice", Amount: "1.5", Hash: "0xaa", Status: "pending"},
+		{Row: 2, To: "bob", Amount: "2", Token: "DAI", Status: BATCH_FAILED, Error: "Send Token error: a, b"},
+	}
+	if err := writeResults(file, sent); err != nil {
+		t.Fatal(err)
+	}
+	rows := []*BatchRow{{Row: 1, To: "alice", Amount: "1.5"}, {Row: 2, To: "bob", Amount: "2", Token: "DAI"}}
+	if err := readResults(file, rows); err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range rows {
+		if *row != *sent[i] {
+			t.Errorf("row %d = %+v, want %+v", i, *row, *sent[i])
+		}
+	}
+}
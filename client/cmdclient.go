@@ -1,77 +1,518 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"os"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/qxoo/mywallet/config"
 	"github.com/qxoo/mywallet/db"
 	"github.com/qxoo/mywallet/mytoken"
+	"github.com/qxoo/mywallet/units"
 	"github.com/qxoo/mywallet/wallet"
 )
 
-func getDB(dir string) *db.DB {
+func getDB(dir string) (*db.DB, error) {
 	mydb, err := db.NewDB(dir)
 	if err != nil {
-		log.Fatalln("Db load fail: ", err)
+		return nil, fmt.Errorf("Db load fail: %w", err)
 	}
-	return mydb
+	mydb.UseChain(config.Config.ChainID)
+	return mydb, nil
 }
 
-func getAddressByName(dir, name string) string {
-	mydb := getDB(dir)
+// nonceStore opens the database for each update, it must not stay open
+// while a command sends.
+type nonceStore struct {
+	dir string
+}
+
+func (store nonceStore) UpdateNonce(address string, fn func(next uint64, updated time.Time) (uint64, error)) error {
+	mydb, err := getDB(store.dir)
+	if err != nil {
+		return err
+	}
+	defer mydb.Close()
+	return mydb.UpdateNonce(address, fn)
+}
+
+// scanStore opens the database for each call, it must not stay open while a
+// command scans the chain.
+type scanStore struct {
+	dir   string
+	token string
+	owner string
+}
+
+func (store scanStore) GetScanRange() (*db.ScanRange, error) {
+	mydb, err := getDB(store.dir)
+	if err != nil {
+		return nil, err
+	}
+	defer mydb.Close()
+	return mydb.GetScanRange(store.token, store.owner)
+}
+
+func (store scanStore) SaveTokenEvents(events []db.TokenEvent, rng db.ScanRange) error {
+	mydb, err := getDB(store.dir)
+	if err != nil {
+		return err
+	}
+	defer mydb.Close()
+	return mydb.SaveTokenEvents(store.token, store.owner, events, rng)
+}
+
+func (store scanStore) TokenEvents() ([]db.TokenEvent, error) {
+	mydb, err := getDB(store.dir)
+	if err != nil {
+		return nil, err
+	}
+	defer mydb.Close()
+	return mydb.TokenEvents(store.token, store.owner)
+}
+
+func (cli CmdClient) nonces() *wallet.NonceManager {
+	return &wallet.NonceManager{
+		Store: nonceStore{dir: cli.Path},
+		OnGap: func(address common.Address, from, to uint64) {
+			log.Printf("Warning: nonces %d to %d of %s never reached the node, reusing %d\n", from, to, address.Hex(), from)
+		},
+	}
+}
+
+func getAddressByName(dir, name string) (string, error) {
+	mydb, err := getDB(dir)
+	if err != nil {
+		return "", err
+	}
 	defer mydb.Close()
 
 	addr, err := mydb.GetAddress(name)
 	if err != nil {
-		log.Fatalln("Query Db error: ", err)
+		return "", fmt.Errorf("Query Db error: %w", err)
+	}
+	return addr, nil
+}
+
+// resolveAddress accepts a contact name or a hex address for -to.
+func resolveAddress(dir, to string) (string, error) {
+	mydb, err := getDB(dir)
+	if err != nil {
+		return "", err
+	}
+	defer mydb.Close()
+
+	if addr, err := mydb.GetContact(to); err == nil {
+		return addr, nil
+	}
+	address, checksummed, err := wallet.ParseAddress(to)
+	if err != nil {
+		return "", fmt.Errorf("Unknown contact, %w", err)
+	}
+	if !checksummed {
+		log.Println("Warning: address is not checksummed, expected ", address.Hex())
+	}
+	return address.Hex(), nil
+}
+
+// resolveToken accepts a registered token symbol, a symbol from the network
+// tokens or an address for -token, tokens not in the database are reported
+// as not registered.
+func resolveToken(dir, key string) (db.Token, bool, error) {
+	if key == "" {
+		key = config.Config.MytokenAddress
+	}
+	if key == "" {
+		return db.Token{}, false, fmt.Errorf("%w: no -token given and no mytoken_address configured", ErrUsage)
+	}
+
+	mydb, err := getDB(dir)
+	if err != nil {
+		return db.Token{}, false, err
+	}
+	defer mydb.Close()
+
+	if token, err := mydb.FindToken(key); err == nil {
+		return *token, true, nil
+	}
+	if address, ok := config.Config.Token(key); ok {
+		key = address
+	}
+	address, _, err := wallet.ParseAddress(key)
+	if err != nil {
+		return db.Token{}, false, fmt.Errorf("Unknown token: %s", key)
+	}
+	// the configured MyToken has no decimals(), it needs no registration
+	if config.Config.MytokenAddress != "" && address == common.HexToAddress(config.Config.MytokenAddress) {
+		return db.Token{Address: address.Hex(), Symbol: mytoken.MYTOKEN_SYMBOL, Decimals: mytoken.MYTOKEN_DECIMALS}, true, nil
+	}
+	return db.Token{Address: address.Hex()}, false, nil
+}
+
+// loadTokenDecimals reads decimals from the token unless it is registered,
+// a token whose decimals can't be read must be registered first.
+func loadTokenDecimals(tw *mytoken.TokenWallet, token *db.Token, registered bool) error {
+	if registered {
+		return nil
+	}
+	decimals, err := tw.Decimals()
+	if err != nil {
+		return fmt.Errorf("Read Token error: %w, register the token with token add -decimals N", err)
+	}
+	token.Decimals = decimals
+	return nil
+}
+
+func tokenSymbol(token db.Token) string {
+	if token.Symbol == "" {
+		return token.Address
+	}
+	return token.Symbol
+}
+
+func saveHDAccount(mydb *db.DB, name string, w *wallet.Wallet) error {
+	seed, err := wallet.SeedID(w.Words, w.Passphrase)
+	if err != nil {
+		return err
+	}
+	return mydb.SaveHDAccount(name, db.HDAccount{Seed: seed, Path: w.Path.String()})
+}
+
+func getFeeOpts(maxfee, tip string) (wallet.FeeOpts, error) {
+	opts := wallet.FeeOpts{}
+	var err error
+	if maxfee != "" {
+		if opts.MaxFee, err = units.ParseUnit(maxfee, "gwei"); err != nil {
+			return opts, fmt.Errorf("Invalid Max Fee: %w", err)
+		}
+	}
+	if tip != "" {
+		if opts.Tip, err = units.ParseUnit(tip, "gwei"); err != nil {
+			return opts, fmt.Errorf("Invalid Tip: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// getData reads -data as hex when prefixed with 0x, otherwise as text.
+func getData(data string) ([]byte, error) {
+	if strings.HasPrefix(data, "0x") {
+		return hexutil.Decode(data)
+	}
+	return []byte(data), nil
+}
+
+type waitFlags struct {
+	wait          *bool
+	confirmations *uint64
+	timeout       *time.Duration
+}
+
+func addWaitFlags(cmd *flag.FlagSet) waitFlags {
+	return waitFlags{
+		wait:          cmd.Bool("wait", false, "WAIT FOR RECEIPT"),
+		confirmations: cmd.Uint64("confirmations", 0, "CONFIRMATIONS TO WAIT FOR"),
+		timeout:       cmd.Duration("timeout", 5*time.Minute, "WAIT TIMEOUT"),
+	}
+}
+
+// opts returns nil when the command should not wait for the receipt.
+func (f waitFlags) opts() *wallet.WaitOpts {
+	if !*f.wait && *f.confirmations == 0 {
+		return nil
 	}
-	return addr
+	return &wallet.WaitOpts{Confirmations: *f.confirmations, Timeout: *f.timeout}
 }
 
 type CmdClient struct {
 	Url  string
 	Path string
+	out  *output
 }
 
 func NewCmdClient(url, path string) *CmdClient {
-	return &CmdClient{Url: url, Path: path}
+	return &CmdClient{Url: url, Path: path, out: &output{format: OUTPUT_TEXT}}
+}
+
+// saveTx only warns on failure, the transaction is already sent.
+func (cli CmdClient) saveTx(tx *types.Transaction, record db.Transaction) {
+	record.Hash = tx.Hash().Hex()
+	record.Nonce = tx.Nonce()
+	record.Status = db.TxPending
+	record.Timestamp = time.Now()
+
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		log.Println("Save History error: ", err)
+		return
+	}
+	defer mydb.Close()
+
+	if err := mydb.SaveTx(record); err != nil {
+		log.Println("Save History error: ", err)
+	}
+}
+
+// refreshPending settles the pending records in txs, and in the database,
+// from their receipts. A transaction and its speedup or cancel replacements
+// share a nonce and are settled together: the one mined gets its status and
+// the pending others are dropped, as are pending records whose nonce the
+// account has used already. Records stay pending when the node can't be
+// reached.
+func (cli CmdClient) refreshPending(txs []db.Transaction) {
+	byhash := map[string]*db.Transaction{}
+	for i := range txs {
+		byhash[txs[i].Hash] = &txs[i]
+	}
+	// group the records by the first transaction of their replacement chain
+	chains := map[string][]*db.Transaction{}
+	roots, pending := []string{}, map[string]bool{}
+	for i := range txs {
+		root := &txs[i]
+		for root.Replaces != "" && byhash[root.Replaces] != nil {
+			root = byhash[root.Replaces]
+		}
+		if _, ok := chains[root.Hash]; !ok {
+			roots = append(roots, root.Hash)
+		}
+		chains[root.Hash] = append(chains[root.Hash], &txs[i])
+		if txs[i].Status == db.TxPending {
+			pending[root.Hash] = true
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	client, err := ethclient.Dial(cli.Url)
+	if err != nil {
+		log.Println("Warning: pending transactions not updated: ", wallet.Classify(err))
+		return
+	}
+	defer client.Close()
+
+	settled := []*db.Transaction{}
+	nonces := map[string]uint64{}
+	settle := func(chain []*db.Transaction) error {
+		for _, tx := range chain {
+			if tx.Status != db.TxPending && tx.Status != db.TxReplaced {
+				continue
+			}
+			receipt, err := wallet.FetchReceipt(client, common.HexToHash(tx.Hash))
+			if errors.Is(err, ethereum.NotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			tx.Status, tx.Block, tx.Fee = db.TxSuccess, receipt.BlockNumber.Uint64(), receipt.Fee.String()
+			if !receipt.Success() {
+				tx.Status = db.TxReverted
+			}
+			settled = append(settled, tx)
+			for _, other := range chain {
+				if other.Status == db.TxPending {
+					other.Status = db.TxDropped
+					settled = append(settled, other)
+				}
+			}
+			return nil
+		}
+
+		// nothing mined, the nonce of records from another network says nothing
+		first := chain[0]
+		if first.ChainID == 0 || first.ChainID != config.Config.ChainID {
+			return nil
+		}
+		nonce, ok := nonces[first.From]
+		if !ok {
+			nonce, err = client.NonceAt(context.Background(), common.HexToAddress(first.From), nil)
+			if err != nil {
+				return err
+			}
+			nonces[first.From] = nonce
+		}
+		if nonce <= first.Nonce {
+			return nil
+		}
+		for _, tx := range chain {
+			if tx.Status == db.TxPending {
+				tx.Status = db.TxDropped
+				settled = append(settled, tx)
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if !pending[root] {
+			continue
+		}
+		if err := settle(chains[root]); err != nil {
+			log.Println("Warning: pending transactions not updated: ", wallet.Classify(err))
+			break
+		}
+	}
+	if len(settled) == 0 {
+		return
+	}
+
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		log.Println("Save History error: ", err)
+		return
+	}
+	defer mydb.Close()
+	for _, tx := range settled {
+		if err := mydb.UpdateTxStatus(tx.Hash, tx.Status, tx.Block, tx.Fee); err != nil {
+			log.Println("Save History error: ", err)
+		}
+	}
+}
+
+// saveReceipt records the status, block and fee of the mined tx in history
+// and returns the status.
+func (cli CmdClient) saveReceipt(tx *types.Transaction, receipt *wallet.Receipt) string {
+	status := db.TxSuccess
+	if !receipt.Success() {
+		status = db.TxReverted
+	}
+	if mydb, err := getDB(cli.Path); err != nil {
+		log.Println("Save History error: ", err)
+	} else {
+		err = mydb.UpdateTxStatus(tx.Hash().Hex(), status, receipt.BlockNumber.Uint64(), receipt.Fee.String())
+		mydb.Close()
+		if err != nil {
+			log.Println("Save History error: ", err)
+		}
+	}
+	return status
+}
+
+func (cli CmdClient) waitReceipt(client *ethclient.Client, tx *types.Transaction, opts *wallet.WaitOpts) (*TxResult, error) {
+	result := &TxResult{Hash: tx.Hash().Hex(), Status: db.TxPending, Url: config.Config.TxUrl(tx.Hash().Hex())}
+	cli.println("Transcation Address: ", result.Hash)
+	if result.Url != "" {
+		cli.println("Explorer: ", result.Url)
+	}
+	if opts == nil {
+		return result, nil
+	}
+
+	receipt, err := wallet.WaitMined(client, tx, *opts)
+	if err != nil {
+		return result, fmt.Errorf("Wait Receipt error: %w", err)
+	}
+
+	result.Status = cli.saveReceipt(tx, receipt)
+	result.Block = receipt.BlockNumber.Uint64()
+	result.GasUsed = receipt.GasUsed
+	result.Fee = units.FormatEther(receipt.Fee)
+	cli.println("Block: ", receipt.BlockNumber)
+	cli.println("Gas Used: ", receipt.GasUsed)
+	cli.println("Fee: ", result.Fee, "ETH")
+	cli.println("Status: ", receipt.StatusText())
+	if !receipt.Success() {
+		return result, fmt.Errorf("%w: %s", wallet.ErrReverted, tx.Hash().Hex())
+	}
+	return result, nil
+}
+
+func tokenSendResult(kind, from, to string, token db.Token, amount *big.Int) SendResult {
+	return SendResult{
+		Kind: kind, From: from, To: to, Token: token.Address, Symbol: tokenSymbol(token),
+		Value: units.Format(amount, int(token.Decimals)), Raw: amount.String(),
+	}
+}
+
+// sendResult waits for tx as requested and keeps result for json output.
+func (cli CmdClient) sendResult(result SendResult, client *ethclient.Client, tx *types.Transaction, wait *wallet.WaitOpts) error {
+	var err error
+	result.Tx, err = cli.waitReceipt(client, tx, wait)
+	cli.setResult(result)
+	return err
+}
+
+// unknown prints the help for text output, json output reports a usage error.
+func (cli CmdClient) unknown(args []string) error {
+	if cli.out.json() {
+		return fmt.Errorf("%w: unknown command %s", ErrUsage, strings.Join(args, " "))
+	}
+	cli.Help()
+	return nil
 }
 
 func (cli CmdClient) Help() {
+	fmt.Println("Usage: mywallet [-config FILE] [-network NAME] [-output text|json] COMMAND [ARGS]")
+	fmt.Println()
 	fmt.Println("Command")
 	fmt.Println()
-	fmt.Println("\tcreate -pass PASSWORD -name NAME --for create new wallet")
+	fmt.Println("\tcreate [-pass-file FILE] -name NAME [-seedpass PASSPHRASE] [-account N -change N -index N] [-savewords] --for create new wallet")
 	fmt.Println("\tshow --for show all wallet")
-	fmt.Println("\tdelete -pass PASSWROD -name NAME --for delete wallet")
-	fmt.Println("\timport -words \"xx xx xx ... \" --for import wallet by mnemonic")
-	fmt.Println("\tbalance -pass PASSWROD -name NAME --for query account balance")
-	fmt.Println("\ttransfer -pass PASSWORD -name NAME -to TOADDR -value VALUE --for transfer from acct to toaddr")
+	fmt.Println("\tdelete [-pass-file FILE] -name NAME --for delete wallet")
+	fmt.Println("\timport [-pass-file FILE] -words \"xx xx xx ... \" [-seedpass PASSPHRASE] [-account N -change N -index N] [-savewords] --for import wallet by mnemonic")
+	fmt.Println("\tderive [-pass-file FILE] [-from-pass-file FILE] -from NAME -name NAME [-words \"xx xx xx ... \"] [-seedpass PASSPHRASE] [-index N] [-savewords] --for derive next account of wallet seed")
+	fmt.Println("\treveal [-pass-file FILE] -name NAME --for show stored mnemonic of wallet")
+	fmt.Println("\tbalance -name NAME --for query account balance")
+	fmt.Println("\tcontact add -name NAME -address ADDRESS --for add address book contact")
+	fmt.Println("\tcontact list --for show address book")
+	fmt.Println("\tcontact remove -name NAME --for remove address book contact")
+	fmt.Println("\thistory -name NAME [-direction in|out -status STATUS -since YYYY-MM-DD -until YYYY-MM-DD] --for show sent transactions")
+	fmt.Println("\twatch [-confirmations N -interval DURATION -json] --for watch eth and token transfers of all wallets")
+	fmt.Println("\ttransfer [-pass-file FILE] -name NAME -to TOADDR -value VALUE[ether|gwei|wei] [-maxfee GWEI -tip GWEI] [-data DATA] [-wait] --for transfer from acct to toaddr")
+	fmt.Println("\tbatch [-pass-file FILE] -name NAME -file PAYOUTS.csv [-out RESULTS.csv] [-yes] [-wait] --for pay rows of to,amount[,token] from acct")
+	fmt.Println("\tspeedup [-pass-file FILE] -hash HASH [-maxfee GWEI -tip GWEI] [-wait] --for resend pending transaction with higher fee")
+	fmt.Println("\tcancel [-pass-file FILE] -hash HASH [-maxfee GWEI -tip GWEI] [-wait] --for replace pending transaction with empty transfer to self")
+	fmt.Println("\tsign [-pass-file FILE] -name NAME -to TOADDR -value VALUE -nonce N (-gasprice GWEI | -maxfee GWEI -tip GWEI) [-gas N] [-chainid N] [-data DATA] [-format raw|json] [-out FILE] --for sign transfer offline")
+	fmt.Println("\tprepare -from NAME|ADDRESS [-token SYMBOL|ADDRESS] -to TOADDR -value VALUE [-maxfee GWEI -tip GWEI] [-data DATA] [-out FILE] --for write unsigned transfer or sendtoken")
+	fmt.Println("\tsign [-pass-file FILE] -file FILE [-format raw|json] [-out FILE] --for sign transaction written by prepare")
+	fmt.Println("\tbroadcast -file FILE [-wait] --for send transaction signed by sign")
 	fmt.Println()
 	fmt.Println("Token Command")
 	fmt.Println()
-	fmt.Println("\tdeploytoken -pass PASSWORD -name NAME --for deploy token")
-	fmt.Println("\tminttoken -pass PASSWORD -name NAME -to TOADDR -value VALUE --for mint token to toaddr")
-	fmt.Println("\tsendtoken -pass PASSWORD -name NAME -to TOADDR -value VALUE --for send mytoken")
-	fmt.Println("\tbalancetoken -name NAME --for query account balance")
+	fmt.Println("\ttoken add -address ADDRESS [-decimals N] --for register erc20 token")
+	fmt.Println("\ttoken list --for show registered tokens")
+	fmt.Println("\ttoken remove -token SYMBOL|ADDRESS --for remove registered token")
+	fmt.Println("\tdeploytoken [-pass-file FILE] -name NAME [-wait] --for deploy token")
+	fmt.Println("\tminttoken [-pass-file FILE] -name NAME -to TOADDR -value VALUE [-wait] --for mint token to toaddr")
+	fmt.Println("\tsendtoken [-pass-file FILE] -name NAME [-token SYMBOL|ADDRESS] -to TOADDR -value VALUE [-wait] --for send token")
+	fmt.Println("\tapprovetoken [-pass-file FILE] -name NAME [-token SYMBOL|ADDRESS] -spender ADDR -value VALUE [-safe] [-wait] --for approve spender")
+	fmt.Println("\tallowancetoken -name NAME [-token SYMBOL|ADDRESS] -spender ADDR --for query allowance of spender")
+	fmt.Println("\ttransferfromtoken [-pass-file FILE] -name NAME [-token SYMBOL|ADDRESS] -from OWNER -to TOADDR -value VALUE [-wait] --for send token of owner by allowance")
+	fmt.Println("\ttokenhistory -name NAME [-token SYMBOL|ADDRESS] [-start BLOCK -chunk N -confirmations N] --for show token transfers with running balance")
+	fmt.Println("\tbalancetoken -name NAME [-token SYMBOL|ADDRESS] --for query account token balance")
 }
 
-func (cli CmdClient) CreateWallet(pass string, name string) {
-	mydb := getDB(cli.Path)
+func (cli CmdClient) CreateWallet(pass string, name string, seedpass string, hdpath accounts.DerivationPath, savewords bool) error {
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
 	defer mydb.Close()
 
 	exists, err := mydb.Exists(name)
 	if exists {
-		log.Fatalln("Name Repeat: ", name)
+		return fmt.Errorf("%w: %s", db.ErrNameExists, name)
 	}
 	if err != nil {
-		log.Fatalln("Query DB error: ", err)
+		return fmt.Errorf("Query DB error: %w", err)
 	}
 
-	w, err := wallet.NewWallet(cli.Path, pass)
+	w, err := wallet.NewWallet(cli.Path, pass, seedpass, hdpath)
 	if err != nil {
-		log.Fatalln("Create Fail: ", err)
+		return fmt.Errorf("Create Fail: %w", err)
 	}
 
 	address := w.Account.Address.Hex()
@@ -79,64 +520,123 @@ func (cli CmdClient) CreateWallet(pass string, name string) {
 		name = address
 	}
 	if err := mydb.SaveAddress(name, address); err != nil {
-		log.Fatalln("Query DB error: ", err)
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	if err := saveHDAccount(mydb, name, w); err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	if savewords {
+		if err := mydb.SaveMnemonic(name, w.Words, pass); err != nil {
+			return fmt.Errorf("Save Mnemonic error: %w", err)
+		}
+	}
+	cli.setResult(WalletResult{Name: name, Address: address, Path: w.Path.String(), Mnemonic: w.Words})
+	cli.println("Path: ", w.Path)
+	cli.println("Create Wallet: ", w.Words)
+	return nil
+}
+
+func (cli CmdClient) Reveal(pass string, name string) error {
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	defer mydb.Close()
+
+	stored, err := mydb.HasMnemonic(name)
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
 	}
-	fmt.Println("Create Wallet: ", w.Words)
+	if !stored {
+		return fmt.Errorf("Mnemonic not stored: %s", name)
+	}
+	if !confirm(fmt.Sprintf("Reveal mnemonic of %s? Anyone who sees it controls the wallet.", name)) {
+		return fmt.Errorf("Reveal canceled")
+	}
+
+	words, err := mydb.GetMnemonic(name, pass)
+	if err != nil {
+		return fmt.Errorf("Reveal Mnemonic error: %w", err)
+	}
+	cli.setResult(WalletResult{Name: name, Mnemonic: words})
+	cli.println("Mnemonic: ", words)
+	return nil
 }
 
-func (cli CmdClient) Show() {
-	mydb := getDB(cli.Path)
+func (cli CmdClient) Show() error {
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
 	defer mydb.Close()
 
 	data, err := mydb.GetAll()
 	if err != nil {
-		log.Fatalln("Query DB error: ", err)
+		return fmt.Errorf("Query DB error: %w", err)
 	}
 
-	fmt.Println("Wallet List")
-	fmt.Println()
-	fmt.Println("\tName \tAddress")
-	fmt.Println("----------------------------------")
+	cli.setResult(addressList(data))
+	cli.println("Wallet List")
+	cli.println()
+	cli.println("\tName \tAddress")
+	cli.println("----------------------------------")
 	for k, v := range data {
-		fmt.Printf("\t%s \t%s\n", k, v)
+		cli.printf("\t%s \t%s\n", k, v)
 	}
+	return nil
 }
 
-func (cli CmdClient) DeleteWallet(pass string, name string) {
-	mydb := getDB(cli.Path)
+func (cli CmdClient) DeleteWallet(pass string, name string) error {
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
 	defer mydb.Close()
 
 	addr, err := mydb.GetAddress(name)
 	if err != nil {
-		log.Fatalln("Query Db error: ", err)
+		return fmt.Errorf("Query Db error: %w", err)
 	}
 
 	err = wallet.DeleteWallet(cli.Path, pass, addr)
 	if err != nil {
-		log.Fatalln("Delete Wallet error: ", err)
+		return fmt.Errorf("Delete Wallet error: %w", err)
 	}
 	err = mydb.Delete(name)
 	if err != nil {
-		log.Fatalln("Delete Wallet error: ", err)
+		return fmt.Errorf("Delete Wallet error: %w", err)
+	}
+	err = mydb.DeleteHDAccount(name)
+	if err != nil {
+		return fmt.Errorf("Delete Wallet error: %w", err)
 	}
-	fmt.Println("Delete Wallet Success: ", name)
+	err = mydb.DeleteMnemonic(name)
+	if err != nil {
+		return fmt.Errorf("Delete Wallet error: %w", err)
+	}
+	cli.setResult(WalletResult{Name: name, Address: addr})
+	cli.println("Delete Wallet Success: ", name)
+	return nil
 }
 
-func (cli CmdClient) ImportWallet(pass string, name string, words string) {
-	mydb := getDB(cli.Path)
+func (cli CmdClient) ImportWallet(pass string, name string, words string, seedpass string, hdpath accounts.DerivationPath, savewords bool) error {
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
 	defer mydb.Close()
 
 	exists, err := mydb.Exists(name)
 	if exists {
-		log.Fatalln("Name Repeat: ", name)
+		return fmt.Errorf("%w: %s", db.ErrNameExists, name)
 	}
 	if err != nil {
-		log.Fatalln("Query DB error: ", err)
+		return fmt.Errorf("Query DB error: %w", err)
 	}
 
-	w, err := wallet.ImportWallet(words, cli.Path, pass)
+	w, err := wallet.ImportWallet(words, cli.Path, pass, seedpass, hdpath)
 	if err != nil {
-		log.Fatalln("Create Fail: ", err)
+		return fmt.Errorf("Create Fail: %w", err)
 	}
 
 	address := w.Account.Address.Hex()
@@ -144,205 +644,1337 @@ func (cli CmdClient) ImportWallet(pass string, name string, words string) {
 		name = address
 	}
 	if err := mydb.SaveAddress(name, address); err != nil {
-		log.Fatalln("Query DB error: ", err)
+		return fmt.Errorf("Query DB error: %w", err)
 	}
-	fmt.Println("Import Wallet: ", name)
+	if err := saveHDAccount(mydb, name, w); err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	if savewords {
+		if err := mydb.SaveMnemonic(name, w.Words, pass); err != nil {
+			return fmt.Errorf("Save Mnemonic error: %w", err)
+		}
+	}
+	cli.setResult(WalletResult{Name: name, Address: address, Path: w.Path.String()})
+	cli.println("Import Wallet: ", name)
+	cli.println("Path: ", w.Path)
+	return nil
 }
 
-func (cli CmdClient) Transfer(pass string, name string, toaddr string, value float64) {
-	addr := getAddressByName(cli.Path, name)
+// DeriveWallet derives a wallet from the seed of from, frompass decrypts
+// the mnemonic stored for from when words are not given and pass is the
+// password of the new wallet.
+func (cli CmdClient) DeriveWallet(pass string, frompass string, from string, name string, words string, seedpass string, account, change, index int, savewords bool) error {
+	// -1 takes the level from -from, or the next unused index
+	if account < -1 || change < -1 || index < -1 {
+		return fmt.Errorf("%w: -account, -change and -index can't be negative", ErrUsage)
+	}
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	defer mydb.Close()
 
-	w, err := wallet.LoadWallet(cli.Path, pass, addr)
+	exists, err := mydb.Exists(name)
+	if exists {
+		return fmt.Errorf("%w: %s", db.ErrNameExists, name)
+	}
 	if err != nil {
-		log.Fatalln("Load Wallet error: ", err)
+		return fmt.Errorf("Query DB error: %w", err)
 	}
 
-	err = w.InitEthClient(cli.Url)
+	// the stored mnemonic of -from replaces -words, and is kept for the new wallet
+	if words == "" {
+		if words, err = mydb.GetMnemonic(from, frompass); err != nil {
+			return fmt.Errorf("Load Mnemonic error: %w", err)
+		}
+		savewords = true
+	}
+
+	seed, err := wallet.SeedID(words, seedpass)
 	if err != nil {
-		log.Fatalln("Init EthClient error: ", err)
+		return fmt.Errorf("Invalid Words: %w", err)
 	}
-	defer w.CloseClient()
 
-	err = w.Transfer(toaddr, value)
+	// wallets created before HD support were derived at the default path
+	base := accounts.DefaultBaseDerivationPath
+	derived := []string{base.String()}
+	if acc, err := mydb.GetHDAccount(from); err == nil {
+		if acc.Seed != seed {
+			return fmt.Errorf("Words not match wallet: %s", from)
+		}
+		if base, err = accounts.ParseDerivationPath(acc.Path); err != nil {
+			return fmt.Errorf("Invalid Path: %w", err)
+		}
+		derived = []string{}
+	} else {
+		addr, err := mydb.GetAddress(from)
+		if err != nil {
+			return fmt.Errorf("Query Db error: %w", err)
+		}
+		if addr != seed {
+			return fmt.Errorf("Words not match wallet: %s", from)
+		}
+	}
+
+	paths, err := mydb.HDPaths(seed)
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	derived = append(derived, paths...)
+
+	if account < 0 {
+		account = int(base[2] - hdkeychain.HardenedKeyStart)
+	}
+	if change < 0 {
+		change = int(base[3])
+	}
+	hdpath, err := wallet.HDPath(uint64(account), uint64(change), 0)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUsage, err)
+	}
+	if index < 0 {
+		index = int(wallet.NextIndex(hdpath, derived))
+	}
+	if hdpath, err = wallet.HDPath(uint64(account), uint64(change), uint64(index)); err != nil {
+		return fmt.Errorf("%w: %v", ErrUsage, err)
+	}
+
+	w, err := wallet.ImportWallet(words, cli.Path, pass, seedpass, hdpath)
 	if err != nil {
-		log.Fatalln("Transfer error: ", err)
+		return fmt.Errorf("Derive Fail: %w", err)
 	}
-	fmt.Println("Transfer Success.")
+
+	address := w.Account.Address.Hex()
+	if name == "" {
+		name = address
+	}
+	if err := mydb.SaveAddress(name, address); err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	if err := saveHDAccount(mydb, name, w); err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	if savewords {
+		if err := mydb.SaveMnemonic(name, w.Words, pass); err != nil {
+			return fmt.Errorf("Save Mnemonic error: %w", err)
+		}
+	}
+	cli.setResult(WalletResult{Name: name, Address: address, Path: w.Path.String()})
+	cli.println("Derive Wallet: ", name)
+	cli.println("Path: ", w.Path)
+	cli.println("Address: ", address)
+	return nil
 }
 
-func (cli CmdClient) GetBalance(pass string, name string) {
-	addr := getAddressByName(cli.Path, name)
+func (cli CmdClient) Transfer(pass string, name string, toaddr string, value string, data string, opts wallet.FeeOpts, wait *wallet.WaitOpts) error {
+	amount, err := units.ParseUnit(value, "ether")
+	if err != nil {
+		return fmt.Errorf("Invalid Value: %w", err)
+	}
+	payload, err := getData(data)
+	if err != nil {
+		return fmt.Errorf("Invalid Data: %w", err)
+	}
+
+	toaddr, err = resolveAddress(cli.Path, toaddr)
+	if err != nil {
+		return err
+	}
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
 
 	w, err := wallet.LoadWallet(cli.Path, pass, addr)
 	if err != nil {
-		log.Fatalln("Load Wallet error: ", err)
+		return fmt.Errorf("Load Wallet error: %w", err)
 	}
+	w.Nonces = cli.nonces()
 
 	err = w.InitEthClient(cli.Url)
 	if err != nil {
-		log.Fatalln("Init EthClient error: ", err)
+		return fmt.Errorf("Init EthClient error: %w", err)
 	}
 	defer w.CloseClient()
 
-	balance, err := w.GetBalance()
+	tx, err := w.Transfer(toaddr, amount, payload, opts)
 	if err != nil {
-		log.Fatalln("Get Balacne error: ", err)
+		return fmt.Errorf("Transfer error: %w", err)
+	}
+	cli.saveTx(tx, db.Transaction{
+		Kind:  "transfer",
+		From:  addr,
+		To:    common.HexToAddress(toaddr).Hex(),
+		Value: amount.String(),
+	})
+	result := SendResult{
+		Kind: "transfer", From: addr, To: common.HexToAddress(toaddr).Hex(),
+		Symbol: "ETH", Value: units.FormatEther(amount), Raw: amount.String(),
+	}
+	result.Tx, err = cli.waitReceipt(w.Client, tx, wait)
+	cli.setResult(result)
+	if err != nil {
+		return err
+	}
+	if wait == nil {
+		cli.println("Transfer Sent.")
+		return nil
 	}
-	fmt.Println("Balance: ", balance)
+	cli.println("Transfer Success.")
+	return nil
 }
 
-func (cli CmdClient) DeployToken(pass string, name string) {
-	addr := getAddressByName(cli.Path, name)
-
-	mytoken_w, err := mytoken.NewTokenWallet(cli.Url, cli.Path, pass, addr)
+func (cli CmdClient) GetBalance(name string) error {
+	addr, err := getAddressByName(cli.Path, name)
 	if err != nil {
-		log.Fatalln("Load Token Wallet error: ", err)
+		return err
 	}
 
-	token_addr, tx_addr, err := mytoken_w.Deploy()
+	w, err := wallet.LoadWallet(cli.Path, "", addr)
 	if err != nil {
-		log.Fatalln("Deploy Token error: ", err)
+		return fmt.Errorf("Load Wallet error: %w", err)
 	}
-	fmt.Println("Token Address: ", token_addr)
-	fmt.Println("Transcation Address: ", tx_addr)
-}
 
-func (cli CmdClient) MintToken(pass string, name string, toaddr string, value int64) {
-	addr := getAddressByName(cli.Path, name)
-
-	mytoken_w, err := mytoken.NewTokenWallet(cli.Url, cli.Path, pass, addr)
+	err = w.InitEthClient(cli.Url)
 	if err != nil {
-		log.Fatalln("Load Token Wallet error: ", err)
+		return fmt.Errorf("Init EthClient error: %w", err)
 	}
+	defer w.CloseClient()
 
-	tx_addr, err := mytoken_w.Mint(toaddr, value)
+	balance, err := w.GetBalance()
 	if err != nil {
-		log.Fatalln("Mint Token error: ", err)
+		return fmt.Errorf("Get Balacne error: %w", err)
 	}
-	fmt.Println("Transcation Address: ", tx_addr)
+	cli.setResult(BalanceResult{Name: name, Address: addr, Symbol: "ETH", Balance: units.FormatEther(balance), Raw: balance.String()})
+	cli.println("Balance: ", units.FormatEther(balance), "ETH")
+	return nil
 }
 
-func (cli CmdClient) SendToken(pass string, name string, toaddr string, value int64) {
-	addr := getAddressByName(cli.Path, name)
+func (cli CmdClient) DeployToken(pass string, name string, wait *wallet.WaitOpts) error {
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
 
-	mytoken_w, err := mytoken.NewTokenWallet(cli.Url, cli.Path, pass, addr)
+	mytoken_w, err := mytoken.NewTokenWallet(cli.Url, cli.Path, pass, addr, "")
 	if err != nil {
-		log.Fatalln("Load Token Wallet error: ", err)
+		return fmt.Errorf("Load Token Wallet error: %w", err)
 	}
+	defer mytoken_w.Close()
+	mytoken_w.UseNonces(cli.nonces())
 
-	tx_addr, err := mytoken_w.Transfer(toaddr, value)
+	token_addr, tx, err := mytoken_w.Deploy()
 	if err != nil {
-		log.Fatalln("Send Token error: ", err)
+		return fmt.Errorf("Deploy Token error: %w", err)
 	}
-	fmt.Println("Transcation Address: ", tx_addr)
+	cli.saveTx(tx, db.Transaction{
+		Kind:  "deploy",
+		From:  addr,
+		To:    token_addr.Hex(),
+		Value: "0",
+		Token: token_addr.Hex(),
+	})
+	cli.println("Token Address: ", token_addr.Hex())
+	// MyToken can't report its decimals, register them with the token
+	if mydb, err := getDB(cli.Path); err != nil {
+		log.Println("Register Token error: ", err)
+	} else {
+		err = mydb.SaveToken(db.Token{Address: token_addr.Hex(), Symbol: mytoken.MYTOKEN_SYMBOL, Decimals: mytoken.MYTOKEN_DECIMALS})
+		mydb.Close()
+		if err != nil {
+			log.Println("Register Token error: ", err)
+		}
+	}
+	return cli.sendResult(SendResult{Kind: "deploy", From: addr, To: token_addr.Hex(), Token: token_addr.Hex(), Value: "0", Raw: "0"}, mytoken_w.Client(), tx, wait)
 }
 
-func (cli CmdClient) BalanceToken(name string) {
-	addr := getAddressByName(cli.Path, name)
+func (cli CmdClient) MintToken(pass string, name string, toaddr string, value string, wait *wallet.WaitOpts) error {
+	toaddr, err := resolveAddress(cli.Path, toaddr)
+	if err != nil {
+		return err
+	}
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
+	token, registered, err := resolveToken(cli.Path, "")
+	if err != nil {
+		return err
+	}
 
-	mytoken_w, err := mytoken.NewTokenWallet(cli.Url, cli.Path, "", addr)
+	mytoken_w, err := mytoken.NewTokenWallet(cli.Url, cli.Path, pass, addr, token.Address)
 	if err != nil {
-		log.Fatalln("Load Token Wallet error: ", err)
+		return fmt.Errorf("Load Token Wallet error: %w", err)
 	}
-	value, err := mytoken_w.Balacne(addr)
+	defer mytoken_w.Close()
+	mytoken_w.UseNonces(cli.nonces())
+
+	if err := loadTokenDecimals(mytoken_w, &token, registered); err != nil {
+		return err
+	}
+	amount, err := units.ParseDecimal(value, int(token.Decimals))
 	if err != nil {
-		log.Fatalln("Get Balace Token error: ", err)
+		return fmt.Errorf("Invalid Value: %w", err)
 	}
-	fmt.Println("Balance Token: ", value)
-}
 
-func (cli CmdClient) Run() {
-	if len(os.Args) < 2 {
-		cli.Help()
-		return
+	tx, err := mytoken_w.Mint(toaddr, amount)
+	if err != nil {
+		return fmt.Errorf("Mint Token error: %w", err)
 	}
+	cli.saveTx(tx, db.Transaction{
+		Kind:  "mint",
+		From:  addr,
+		To:    common.HexToAddress(toaddr).Hex(),
+		Value: amount.String(),
+		Token: mytoken_w.Address().Hex(),
+	})
+	result := tokenSendResult("mint", addr, common.HexToAddress(toaddr).Hex(), token, amount)
+	return cli.sendResult(result, mytoken_w.Client(), tx, wait)
+}
 
-	fmt.Println("==================================")
-	fmt.Println()
-	switch os.Args[1] {
+func (cli CmdClient) SendToken(pass string, name string, tokenkey string, toaddr string, value string, wait *wallet.WaitOpts) error {
+	toaddr, err := resolveAddress(cli.Path, toaddr)
+	if err != nil {
+		return err
+	}
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
+	token, registered, err := resolveToken(cli.Path, tokenkey)
+	if err != nil {
+		return err
+	}
+
+	mytoken_w, err := mytoken.NewTokenWallet(cli.Url, cli.Path, pass, addr, token.Address)
+	if err != nil {
+		return fmt.Errorf("Load Token Wallet error: %w", err)
+	}
+	defer mytoken_w.Close()
+	mytoken_w.UseNonces(cli.nonces())
+
+	if err := loadTokenDecimals(mytoken_w, &token, registered); err != nil {
+		return err
+	}
+	amount, err := units.ParseDecimal(value, int(token.Decimals))
+	if err != nil {
+		return fmt.Errorf("Invalid Value: %w", err)
+	}
+
+	tx, err := mytoken_w.Transfer(toaddr, amount)
+	if err != nil {
+		return fmt.Errorf("Send Token error: %w", err)
+	}
+	cli.saveTx(tx, db.Transaction{
+		Kind:  "transfer",
+		From:  addr,
+		To:    common.HexToAddress(toaddr).Hex(),
+		Value: amount.String(),
+		Token: mytoken_w.Address().Hex(),
+	})
+	result := tokenSendResult("transfer", addr, common.HexToAddress(toaddr).Hex(), token, amount)
+	return cli.sendResult(result, mytoken_w.Client(), tx, wait)
+}
+
+func (cli CmdClient) ApproveToken(pass string, name string, tokenkey string, spender string, value string, safe bool, wait *wallet.WaitOpts) error {
+	spender, err := resolveAddress(cli.Path, spender)
+	if err != nil {
+		return err
+	}
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
+	token, registered, err := resolveToken(cli.Path, tokenkey)
+	if err != nil {
+		return err
+	}
+
+	mytoken_w, err := mytoken.NewTokenWallet(cli.Url, cli.Path, pass, addr, token.Address)
+	if err != nil {
+		return fmt.Errorf("Load Token Wallet error: %w", err)
+	}
+	defer mytoken_w.Close()
+	mytoken_w.UseNonces(cli.nonces())
+
+	if err := loadTokenDecimals(mytoken_w, &token, registered); err != nil {
+		return err
+	}
+	amount, err := units.ParseDecimal(value, int(token.Decimals))
+	if err != nil {
+		return fmt.Errorf("Invalid Value: %w", err)
+	}
+
+	var txs []*types.Transaction
+	var reset *wallet.Receipt
+	if safe {
+		reset_wait := wallet.WaitOpts{Confirmations: 1, Timeout: 5 * time.Minute}
+		if wait != nil {
+			reset_wait = *wait
+		}
+		txs, reset, err = mytoken_w.SafeApprove(spender, amount, reset_wait)
+	} else {
+		var tx *types.Transaction
+		tx, err = mytoken_w.Approve(spender, amount)
+		txs = []*types.Transaction{tx}
+	}
+	result := tokenSendResult("approve", addr, spender, token, amount)
+	for i, tx := range txs {
+		if tx == nil {
+			continue
+		}
+		// only the last transaction of a successful approve sets the amount
+		tx_value := "0"
+		if err == nil && i == len(txs)-1 {
+			tx_value = amount.String()
+		}
+		cli.saveTx(tx, db.Transaction{
+			Kind:  "approve",
+			From:  addr,
+			To:    spender,
+			Value: tx_value,
+			Token: mytoken_w.Address().Hex(),
+		})
+		txresult := &TxResult{Hash: tx.Hash().Hex(), Status: db.TxPending}
+		// SafeApprove waited for the reset already
+		if i == 0 && reset != nil {
+			txresult.Status = cli.saveReceipt(tx, reset)
+			txresult.Block, txresult.GasUsed, txresult.Fee = reset.BlockNumber.Uint64(), reset.GasUsed, units.FormatEther(reset.Fee)
+		}
+		result.Txs = append(result.Txs, txresult)
+	}
+	if err != nil {
+		cli.setResult(result)
+		return fmt.Errorf("Approve Token error: %w", err)
+	}
+	result.Tx, err = cli.waitReceipt(mytoken_w.Client(), txs[len(txs)-1], wait)
+	result.Txs[len(result.Txs)-1] = result.Tx
+	cli.setResult(result)
+	if err != nil {
+		return err
+	}
+	cli.println("Approve: ", units.Format(amount, int(token.Decimals)), tokenSymbol(token), "to", spender)
+	return nil
+}
+
+func (cli CmdClient) AllowanceToken(name string, tokenkey string, spender string) error {
+	spender, err := resolveAddress(cli.Path, spender)
+	if err != nil {
+		return err
+	}
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
+	token, registered, err := resolveToken(cli.Path, tokenkey)
+	if err != nil {
+		return err
+	}
+
+	mytoken_w, err := mytoken.NewTokenClient(cli.Url, token.Address)
+	if err != nil {
+		return fmt.Errorf("Load Token Wallet error: %w", err)
+	}
+	defer mytoken_w.Close()
+
+	if err := loadTokenDecimals(mytoken_w, &token, registered); err != nil {
+		return err
+	}
+	value, err := mytoken_w.Allowance(addr, spender)
+	if err != nil {
+		return fmt.Errorf("Get Allowance Token error: %w", err)
+	}
+	cli.setResult(AllowanceResult{
+		Owner: addr, Spender: spender, Token: token.Address, Symbol: tokenSymbol(token),
+		Allowance: units.Format(value, int(token.Decimals)), Raw: value.String(),
+	})
+	cli.println("Allowance Token: ", units.Format(value, int(token.Decimals)), tokenSymbol(token))
+	return nil
+}
+
+func (cli CmdClient) TransferFromToken(pass string, name string, tokenkey string, fromaddr string, toaddr string, value string, wait *wallet.WaitOpts) error {
+	fromaddr, err := resolveAddress(cli.Path, fromaddr)
+	if err != nil {
+		return err
+	}
+	toaddr, err = resolveAddress(cli.Path, toaddr)
+	if err != nil {
+		return err
+	}
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
+	token, registered, err := resolveToken(cli.Path, tokenkey)
+	if err != nil {
+		return err
+	}
+
+	mytoken_w, err := mytoken.NewTokenWallet(cli.Url, cli.Path, pass, addr, token.Address)
+	if err != nil {
+		return fmt.Errorf("Load Token Wallet error: %w", err)
+	}
+	defer mytoken_w.Close()
+	mytoken_w.UseNonces(cli.nonces())
+
+	if err := loadTokenDecimals(mytoken_w, &token, registered); err != nil {
+		return err
+	}
+	amount, err := units.ParseDecimal(value, int(token.Decimals))
+	if err != nil {
+		return fmt.Errorf("Invalid Value: %w", err)
+	}
+
+	tx, err := mytoken_w.TransferFrom(fromaddr, toaddr, amount)
+	if err != nil {
+		return fmt.Errorf("Transfer From Token error: %w", err)
+	}
+	cli.saveTx(tx, db.Transaction{
+		Kind:  "transferfrom",
+		From:  addr,
+		To:    toaddr,
+		Value: amount.String(),
+		Token: mytoken_w.Address().Hex(),
+	})
+	result := tokenSendResult("transferfrom", fromaddr, toaddr, token, amount)
+	return cli.sendResult(result, mytoken_w.Client(), tx, wait)
+}
+
+func (cli CmdClient) BalanceToken(name string, tokenkey string) error {
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
+	token, registered, err := resolveToken(cli.Path, tokenkey)
+	if err != nil {
+		return err
+	}
+
+	mytoken_w, err := mytoken.NewTokenWallet(cli.Url, cli.Path, "", addr, token.Address)
+	if err != nil {
+		return fmt.Errorf("Load Token Wallet error: %w", err)
+	}
+	defer mytoken_w.Close()
+
+	if err := loadTokenDecimals(mytoken_w, &token, registered); err != nil {
+		return err
+	}
+	value, err := mytoken_w.Balacne(addr)
+	if err != nil {
+		return fmt.Errorf("Get Balace Token error: %w", err)
+	}
+	cli.setResult(BalanceResult{
+		Name: name, Address: addr, Token: token.Address, Symbol: tokenSymbol(token),
+		Balance: units.Format(value, int(token.Decimals)), Raw: value.String(),
+	})
+	cli.println("Balance Token: ", units.Format(value, int(token.Decimals)), tokenSymbol(token))
+	return nil
+}
+
+func toTokenEvents(events []mytoken.TransferEvent) []db.TokenEvent {
+	ret := []db.TokenEvent{}
+	for _, event := range events {
+		ret = append(ret, db.TokenEvent{
+			Block:    event.Block,
+			TxHash:   event.TxHash.Hex(),
+			LogIndex: event.LogIndex,
+			From:     event.From.Hex(),
+			To:       event.To.Hex(),
+			Value:    event.Value.String(),
+		})
+	}
+	return ret
+}
+
+// TokenHistory scans the Transfer logs of name from start, blocks with at
+// least confirmations are stored and never scanned again, newer blocks are
+// scanned on every run.
+func (cli CmdClient) TokenHistory(name string, tokenkey string, start uint64, chunk uint64, confirmations uint64) error {
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
+	token, registered, err := resolveToken(cli.Path, tokenkey)
+	if err != nil {
+		return err
+	}
+
+	mytoken_w, err := mytoken.NewTokenClient(cli.Url, token.Address)
+	if err != nil {
+		return fmt.Errorf("Load Token Wallet error: %w", err)
+	}
+	defer mytoken_w.Close()
+
+	if err := loadTokenDecimals(mytoken_w, &token, registered); err != nil {
+		return err
+	}
+	head, err := mytoken_w.BlockNumber()
+	if err != nil {
+		return fmt.Errorf("Get Block Number error: %w", err)
+	}
+
+	store := scanStore{dir: cli.Path, token: token.Address, owner: addr}
+	rng, err := store.GetScanRange()
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	if rng == nil {
+		rng = &db.ScanRange{Start: start, Next: start}
+	}
+
+	// the stored range only grows once the earlier blocks are complete
+	if start < rng.Start {
+		err = mytoken_w.ScanTransfers(addr, start, rng.Start-1, chunk, func(_, _ uint64, events []mytoken.TransferEvent) error {
+			return store.SaveTokenEvents(toTokenEvents(events), *rng)
+		})
+		if err != nil {
+			return fmt.Errorf("Scan Transfer error: %w", err)
+		}
+		rng.Start = start
+	}
+	// blocks from final on can still be reorged, they are not stored
+	final := uint64(0)
+	if head+1 > confirmations {
+		final = head + 1 - confirmations
+	}
+	if rng.Next < final {
+		err = mytoken_w.ScanTransfers(addr, rng.Next, final-1, chunk, func(_, last uint64, events []mytoken.TransferEvent) error {
+			rng.Next = last + 1
+			return store.SaveTokenEvents(toTokenEvents(events), *rng)
+		})
+		if err != nil {
+			return fmt.Errorf("Scan Transfer error: %w", err)
+		}
+	}
+	if err := store.SaveTokenEvents(nil, *rng); err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+
+	events, err := store.TokenEvents()
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	stored := []db.TokenEvent{}
+	for _, event := range events {
+		if event.Block >= rng.Start && event.Block < rng.Next {
+			stored = append(stored, event)
+		}
+	}
+	events, end := stored, rng.Next
+	if end <= head {
+		// a transfer to self is both sent and received, the database keeps it once
+		seen := map[string]bool{}
+		err = mytoken_w.ScanTransfers(addr, end, head, chunk, func(_, _ uint64, recent []mytoken.TransferEvent) error {
+			for _, event := range toTokenEvents(recent) {
+				key := fmt.Sprintf("%d/%d", event.Block, event.LogIndex)
+				if !seen[key] {
+					seen[key] = true
+					events = append(events, event)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("Scan Transfer error: %w", err)
+		}
+		end = head + 1
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Block != events[j].Block {
+			return events[i].Block < events[j].Block
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+
+	balance := big.NewInt(0)
+	if rng.Start > 0 {
+		if balance, err = mytoken_w.BalanceAt(addr, rng.Start-1); err != nil {
+			log.Println("Warning: opening balance unavailable, starting from 0: ", err)
+			balance = big.NewInt(0)
+		}
+	}
+
+	decimals := int(token.Decimals)
+	result := TokenHistoryResult{
+		Name: name, Address: addr, Token: token.Address, Symbol: tokenSymbol(token),
+		Start: rng.Start, End: end - 1, Opening: units.Format(balance, decimals),
+		Events: []TokenHistoryEntry{},
+	}
+	cli.setResult(&result)
+	cli.println("Token History: ", name, tokenSymbol(token))
+	cli.println("Blocks: ", rng.Start, "-", end-1)
+	cli.println()
+	cli.println("\tBlock \tDirection \tAddress \tValue \tBalance \tHash")
+	cli.println("----------------------------------")
+	cli.printf("\t%d \t%s \t%s \t%s \t%s \t%s\n", rng.Start, "open", "", "", units.Format(balance, decimals), "")
+	for _, event := range events {
+		value, _ := new(big.Int).SetString(event.Value, 10)
+		direction, other, amount := "self", event.To, units.Format(value, decimals)
+		switch {
+		case event.From == addr && event.To != addr:
+			direction = "out"
+			balance.Sub(balance, value)
+			amount = "-" + amount
+		case event.To == addr && event.From != addr:
+			direction, other = "in", event.From
+			balance.Add(balance, value)
+			amount = "+" + amount
+		}
+		result.Events = append(result.Events, TokenHistoryEntry{
+			Block: event.Block, Direction: direction, Address: other,
+			Value: amount, Balance: units.Format(balance, decimals), Hash: event.TxHash,
+		})
+		cli.printf("\t%d \t%s \t%s \t%s \t%s \t%s\n", event.Block, direction, other, amount, units.Format(balance, decimals), event.TxHash)
+	}
+	return nil
+}
+
+// AddToken registers the token at address, decimals of -1 are read from the
+// token, tokens without decimals() need them given.
+func (cli CmdClient) AddToken(address string, decimals int) error {
+	addr, _, err := wallet.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+
+	mytoken_w, err := mytoken.NewTokenClient(cli.Url, addr.Hex())
+	if err != nil {
+		return fmt.Errorf("Load Token Wallet error: %w", err)
+	}
+	defer mytoken_w.Close()
+
+	if decimals > 255 {
+		return fmt.Errorf("%w: -decimals %d is above 255", ErrUsage, decimals)
+	}
+	// -decimals only stands in for a token without decimals(), not for a
+	// missing contract or node
+	token_name, symbol, token_decimals, err := mytoken_w.Metadata()
+	if decimals >= 0 && errors.Is(err, mytoken.ErrNoDecimals) {
+		err = nil
+	}
+	if errors.Is(err, mytoken.ErrNoDecimals) {
+		return fmt.Errorf("Read Token error: %w, give them with -decimals N", err)
+	}
+	if err != nil {
+		return fmt.Errorf("Read Token error: %w", err)
+	}
+	if decimals >= 0 {
+		token_decimals = uint8(decimals)
+	}
+
+	token := db.Token{Address: addr.Hex(), Name: token_name, Symbol: symbol, Decimals: token_decimals}
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	defer mydb.Close()
+	if err := mydb.SaveToken(token); err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	cli.setResult(token)
+	cli.println("Add Token: ", token.Symbol, token.Address)
+	cli.println("Name: ", token.Name)
+	cli.println("Decimals: ", token.Decimals)
+	return nil
+}
+
+func (cli CmdClient) ListTokens() error {
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	defer mydb.Close()
+
+	tokens, err := mydb.Tokens()
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+
+	cli.setResult(tokens)
+	cli.println("Token List")
+	cli.println()
+	cli.println("\tSymbol \tDecimals \tAddress \tName")
+	cli.println("----------------------------------")
+	for _, token := range tokens {
+		cli.printf("\t%s \t%d \t%s \t%s\n", token.Symbol, token.Decimals, token.Address, token.Name)
+	}
+	return nil
+}
+
+func (cli CmdClient) RemoveToken(key string) error {
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	defer mydb.Close()
+
+	token, err := mydb.FindToken(key)
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	if err := mydb.DeleteToken(*token); err != nil {
+		return fmt.Errorf("Delete Token error: %w", err)
+	}
+	cli.setResult(token)
+	cli.println("Remove Token: ", token.Symbol, token.Address)
+	return nil
+}
+
+func (cli CmdClient) AddContact(name string, address string) error {
+	if name == "" {
+		return fmt.Errorf("Contact name required")
+	}
+	addr, checksummed, err := wallet.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	if !checksummed {
+		log.Println("Warning: address is not checksummed, expected ", addr.Hex())
+	}
+
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	defer mydb.Close()
+
+	if err := mydb.SaveContact(name, addr.Hex()); err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	cli.setResult(AddressResult{Name: name, Address: addr.Hex()})
+	cli.println("Add Contact: ", name, addr.Hex())
+	return nil
+}
+
+func (cli CmdClient) ListContacts() error {
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	defer mydb.Close()
+
+	data, err := mydb.Contacts()
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+
+	cli.setResult(addressList(data))
+	cli.println("Contact List")
+	cli.println()
+	cli.println("\tName \tAddress")
+	cli.println("----------------------------------")
+	for k, v := range data {
+		cli.printf("\t%s \t%s\n", k, v)
+	}
+	return nil
+}
+
+func (cli CmdClient) RemoveContact(name string) error {
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	defer mydb.Close()
+
+	address, err := mydb.GetContact(name)
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	if err := mydb.DeleteContact(name); err != nil {
+		return fmt.Errorf("Delete Contact error: %w", err)
+	}
+	cli.setResult(AddressResult{Name: name, Address: address})
+	cli.println("Remove Contact: ", name)
+	return nil
+}
+
+func (cli CmdClient) History(name string, direction string, status string, since string, until string) error {
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
+
+	// the status is filtered after pending records are refreshed
+	filter := db.TxFilter{Address: addr, Direction: direction, Status: status}
+	if direction != "" && direction != "in" && direction != "out" {
+		return fmt.Errorf("Invalid Direction: %s", direction)
+	}
+	if since != "" {
+		day, err := time.ParseInLocation("2006-01-02", since, time.Local)
+		if err != nil {
+			return fmt.Errorf("Invalid Since: %w", err)
+		}
+		filter.Since = day
+	}
+	if until != "" {
+		day, err := time.ParseInLocation("2006-01-02", until, time.Local)
+		if err != nil {
+			return fmt.Errorf("Invalid Until: %w", err)
+		}
+		filter.Until = day.AddDate(0, 0, 1)
+	}
+
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	// every record of the address, replacement chains are settled together
+	all, err := mydb.Txs(db.TxFilter{Address: addr})
+	if err != nil {
+		mydb.Close()
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	tokens, err := mydb.Tokens()
+	mydb.Close()
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	cli.refreshPending(all)
+	txs := []db.Transaction{}
+	for _, tx := range all {
+		if filter.Match(tx) {
+			txs = append(txs, tx)
+		}
+	}
+
+	registry := map[string]db.Token{}
+	for _, token := range tokens {
+		registry[token.Address] = token
+	}
+
+	result := HistoryResult{Name: name, Address: addr, Txs: []HistoryEntry{}}
+	cli.setResult(&result)
+	cli.println("Transaction History: ", name)
+	cli.println()
+	cli.println("\tTime \tKind \tDirection \tAddress \tValue \tStatus \tHash")
+	cli.println("----------------------------------")
+	for _, tx := range txs {
+		direction, other := "out", tx.To
+		if tx.From != addr {
+			direction, other = "in", tx.From
+		}
+		value, _ := new(big.Int).SetString(tx.Value, 10)
+		amount, asset := units.FormatEther(value), "ETH"
+		if tx.Token != "" {
+			token, ok := registry[tx.Token]
+			if !ok {
+				token = db.Token{Address: tx.Token}
+			}
+			amount, asset = units.Format(value, int(token.Decimals)), tokenSymbol(token)
+		}
+		result.Txs = append(result.Txs, HistoryEntry{
+			Time: tx.Timestamp, Kind: tx.Kind, Direction: direction, Address: other,
+			Value: amount, Asset: asset, Status: tx.Status, Hash: tx.Hash,
+			Replaces: tx.Replaces, ReplacedBy: tx.ReplacedBy,
+		})
+		cli.printf("\t%s \t%s \t%s \t%s \t%s \t%s \t%s\n",
+			tx.Timestamp.Format("2006-01-02 15:04:05"), tx.Kind, direction, other, amount+" "+asset, tx.Status, tx.Hash)
+	}
+	return nil
+}
+
+// Exec runs the command in args, without the program name, and returns its
+// error instead of exiting.
+func (cli CmdClient) Exec(args []string) error {
+	if len(args) < 1 {
+		return cli.unknown(args)
+	}
+
+	switch args[0] {
 	case "create":
-		cmd := flag.NewFlagSet("create", flag.ExitOnError)
-		cmd_pass := cmd.String("pass", "", "PASSWORD")
+		cmd := flag.NewFlagSet("create", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
 		cmd_name := cmd.String("name", "", "NAME")
-		if err := cmd.Parse(os.Args[2:]); err != nil {
-			log.Fatal("Args Error")
+		cmd_seedpass := cmd.String("seedpass", "", "BIP39 PASSPHRASE")
+		cmd_account := cmd.Uint("account", 0, "ACCOUNT")
+		cmd_change := cmd.Uint("change", 0, "CHANGE")
+		cmd_index := cmd.Uint("index", 0, "INDEX")
+		cmd_savewords := cmd.Bool("savewords", false, "STORE MNEMONIC ENCRYPTED BY PASSWORD")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		hdpath, err := wallet.HDPath(uint64(*cmd_account), uint64(*cmd_change), uint64(*cmd_index))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUsage, err)
 		}
-		cli.CreateWallet(*cmd_pass, *cmd_name)
+		pass, err := cmd_pass.get(true)
+		if err != nil {
+			return err
+		}
+		return cli.CreateWallet(pass, *cmd_name, *cmd_seedpass, hdpath, *cmd_savewords)
 	case "show":
-		cli.Show()
+		return cli.Show()
 	case "delete":
-		cmd := flag.NewFlagSet("delete", flag.ExitOnError)
-		cmd_pass := cmd.String("pass", "", "PASSWORD")
+		cmd := flag.NewFlagSet("delete", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
+		cmd_name := cmd.String("name", "", "NAME")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
+		}
+		return cli.DeleteWallet(pass, *cmd_name)
+	case "reveal":
+		cmd := flag.NewFlagSet("reveal", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
 		cmd_name := cmd.String("name", "", "NAME")
-		if err := cmd.Parse(os.Args[2:]); err != nil {
-			log.Fatal("Args Error")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
 		}
-		cli.DeleteWallet(*cmd_pass, *cmd_name)
+		return cli.Reveal(pass, *cmd_name)
 	case "import":
-		cmd := flag.NewFlagSet("import", flag.ExitOnError)
-		cmd_pass := cmd.String("pass", "", "PASSWORD")
+		cmd := flag.NewFlagSet("import", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
 		cmd_name := cmd.String("name", "", "NAME")
 		cmd_words := cmd.String("words", "", "WORDS")
-		if err := cmd.Parse(os.Args[2:]); err != nil {
-			log.Fatal("Args Error")
+		cmd_seedpass := cmd.String("seedpass", "", "BIP39 PASSPHRASE")
+		cmd_account := cmd.Uint("account", 0, "ACCOUNT")
+		cmd_change := cmd.Uint("change", 0, "CHANGE")
+		cmd_index := cmd.Uint("index", 0, "INDEX")
+		cmd_savewords := cmd.Bool("savewords", false, "STORE MNEMONIC ENCRYPTED BY PASSWORD")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		hdpath, err := wallet.HDPath(uint64(*cmd_account), uint64(*cmd_change), uint64(*cmd_index))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUsage, err)
+		}
+		pass, err := cmd_pass.get(true)
+		if err != nil {
+			return err
+		}
+		return cli.ImportWallet(pass, *cmd_name, *cmd_words, *cmd_seedpass, hdpath, *cmd_savewords)
+	case "derive":
+		cmd := flag.NewFlagSet("derive", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
+		cmd_from := cmd.String("from", "", "NAME")
+		cmd_name := cmd.String("name", "", "NAME")
+		cmd_words := cmd.String("words", "", "WORDS")
+		cmd_seedpass := cmd.String("seedpass", "", "BIP39 PASSPHRASE")
+		cmd_account := cmd.Int("account", -1, "ACCOUNT, default same as -from")
+		cmd_change := cmd.Int("change", -1, "CHANGE, default same as -from")
+		cmd_index := cmd.Int("index", -1, "INDEX, default next unused")
+		cmd_savewords := cmd.Bool("savewords", false, "STORE MNEMONIC ENCRYPTED BY PASSWORD")
+		cmd_from_pass := addFromPassFlags(cmd)
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		// the stored mnemonic of -from is only read without -words
+		frompass := ""
+		if *cmd_words == "" {
+			var err error
+			if frompass, err = cmd_from_pass.get(false); err != nil {
+				return err
+			}
 		}
-		cli.ImportWallet(*cmd_pass, *cmd_name, *cmd_words)
+		pass, err := cmd_pass.get(true)
+		if err != nil {
+			return err
+		}
+		return cli.DeriveWallet(pass, frompass, *cmd_from, *cmd_name, *cmd_words, *cmd_seedpass, *cmd_account, *cmd_change, *cmd_index, *cmd_savewords)
 	case "transfer":
-		cmd := flag.NewFlagSet("transfer", flag.ExitOnError)
-		cmd_pass := cmd.String("pass", "", "PASSWORD")
+		cmd := flag.NewFlagSet("transfer", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
 		cmd_name := cmd.String("name", "", "NAME")
 		cmd_to := cmd.String("to", "", "TOADDR")
-		cmd_value := cmd.Float64("value", 0, "VALUE")
-		if err := cmd.Parse(os.Args[2:]); err != nil {
-			log.Fatal("Args Error")
+		cmd_value := cmd.String("value", "0", "VALUE, e.g. 1.5, 1.5ether, 20gwei, 100wei")
+		cmd_maxfee := cmd.String("maxfee", "", "MAX FEE PER GAS, default gwei")
+		cmd_tip := cmd.String("tip", "", "PRIORITY FEE PER GAS, default gwei")
+		cmd_data := cmd.String("data", "", "DATA, 0x hex or text")
+		cmd_wait := addWaitFlags(cmd)
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		opts, err := getFeeOpts(*cmd_maxfee, *cmd_tip)
+		if err != nil {
+			return err
+		}
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
+		}
+		return cli.Transfer(pass, *cmd_name, *cmd_to, *cmd_value, *cmd_data, opts, cmd_wait.opts())
+	case "sign":
+		cmd := flag.NewFlagSet("sign", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
+		cmd_name := cmd.String("name", "", "NAME")
+		cmd_to := cmd.String("to", "", "TOADDR")
+		cmd_value := cmd.String("value", "0", "VALUE, e.g. 1.5, 1.5ether, 20gwei, 100wei")
+		cmd_data := cmd.String("data", "", "DATA, 0x hex or text")
+		cmd_nonce := cmd.Int64("nonce", -1, "NONCE")
+		cmd_gas := cmd.Uint64("gas", 0, "GAS LIMIT, default 21000 without data")
+		cmd_chainid := cmd.Uint64("chainid", config.Config.ChainID, "CHAIN ID, default chain_id of network")
+		cmd_gasprice := cmd.String("gasprice", "", "LEGACY GAS PRICE, default gwei")
+		cmd_maxfee := cmd.String("maxfee", "", "MAX FEE PER GAS, default gwei")
+		cmd_tip := cmd.String("tip", "", "PRIORITY FEE PER GAS, default gwei")
+		cmd_format := cmd.String("format", FORMAT_RAW, "FILE FORMAT, raw|json")
+		cmd_out := cmd.String("out", "", "OUTPUT FILE, default stdout")
+		cmd_file := cmd.String("file", "", "UNSIGNED TRANSACTION FILE FROM prepare")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		if *cmd_file != "" {
+			pass, err := cmd_pass.get(false)
+			if err != nil {
+				return err
+			}
+			return cli.SignPrepared(pass, *cmd_file, *cmd_format, *cmd_out)
+		}
+		payload, err := getData(*cmd_data)
+		if err != nil {
+			return fmt.Errorf("Invalid Data: %w", err)
+		}
+		txparams, err := getTxParams(*cmd_nonce, *cmd_gas, *cmd_chainid, *cmd_gasprice, *cmd_maxfee, *cmd_tip, payload)
+		if err != nil {
+			return err
+		}
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
+		}
+		return cli.SignTx(pass, *cmd_name, *cmd_to, *cmd_value, *cmd_data, txparams, *cmd_format, *cmd_out)
+	case "prepare":
+		cmd := flag.NewFlagSet("prepare", flag.ContinueOnError)
+		cmd_from := cmd.String("from", "", "NAME|ADDRESS")
+		cmd_token := cmd.String("token", "", "SYMBOL|ADDRESS, prepare sendtoken instead of transfer")
+		cmd_to := cmd.String("to", "", "TOADDR")
+		cmd_value := cmd.String("value", "0", "VALUE, ether or token units")
+		cmd_data := cmd.String("data", "", "DATA, 0x hex or text")
+		cmd_maxfee := cmd.String("maxfee", "", "MAX FEE PER GAS, default gwei")
+		cmd_tip := cmd.String("tip", "", "PRIORITY FEE PER GAS, default gwei")
+		cmd_out := cmd.String("out", "", "OUTPUT FILE, default stdout")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		opts, err := getFeeOpts(*cmd_maxfee, *cmd_tip)
+		if err != nil {
+			return err
+		}
+		return cli.Prepare(*cmd_from, *cmd_token, *cmd_to, *cmd_value, *cmd_data, opts, *cmd_out)
+	case "broadcast":
+		cmd := flag.NewFlagSet("broadcast", flag.ContinueOnError)
+		cmd_file := cmd.String("file", "", "SIGNED TRANSACTION FILE")
+		cmd_wait := addWaitFlags(cmd)
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		return cli.Broadcast(*cmd_file, cmd_wait.opts())
+	case "speedup", "cancel":
+		cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
+		cmd_hash := cmd.String("hash", "", "TRANSACTION HASH")
+		cmd_maxfee := cmd.String("maxfee", "", "MAX FEE PER GAS OR LEGACY GAS PRICE, default gwei")
+		cmd_tip := cmd.String("tip", "", "PRIORITY FEE PER GAS, default gwei")
+		cmd_wait := addWaitFlags(cmd)
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		opts, err := getFeeOpts(*cmd_maxfee, *cmd_tip)
+		if err != nil {
+			return err
+		}
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
+		}
+		return cli.Replace(pass, *cmd_hash, args[0] == "cancel", opts, cmd_wait.opts())
+	case "batch":
+		cmd := flag.NewFlagSet("batch", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
+		cmd_name := cmd.String("name", "", "NAME")
+		cmd_file := cmd.String("file", "", "PAYOUTS CSV, rows of to,amount[,token]")
+		cmd_out := cmd.String("out", "", "RESULTS CSV, default FILE.results.csv")
+		cmd_yes := cmd.Bool("yes", false, "SEND WITHOUT CONFIRMATION")
+		cmd_wait := addWaitFlags(cmd)
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
 		}
-		cli.Transfer(*cmd_pass, *cmd_name, *cmd_to, *cmd_value)
+		return cli.Batch(pass, *cmd_name, *cmd_file, *cmd_out, *cmd_yes, cmd_wait.opts())
 	case "balance":
-		cmd := flag.NewFlagSet("balace", flag.ExitOnError)
-		cmd_pass := cmd.String("pass", "", "PASSWORD")
+		cmd := flag.NewFlagSet("balace", flag.ContinueOnError)
+		cmd_name := cmd.String("name", "", "NAME")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		return cli.GetBalance(*cmd_name)
+	case "contact":
+		if len(args) < 2 {
+			return cli.unknown(args)
+		}
+		cmd := flag.NewFlagSet("contact", flag.ContinueOnError)
+		cmd_name := cmd.String("name", "", "NAME")
+		cmd_address := cmd.String("address", "", "ADDRESS")
+		if err := cmd.Parse(args[2:]); err != nil {
+			return usageError(err)
+		}
+		switch args[1] {
+		case "add":
+			return cli.AddContact(*cmd_name, *cmd_address)
+		case "list":
+			return cli.ListContacts()
+		case "remove":
+			return cli.RemoveContact(*cmd_name)
+		default:
+			return cli.unknown(args)
+		}
+	case "history":
+		cmd := flag.NewFlagSet("history", flag.ContinueOnError)
+		cmd_name := cmd.String("name", "", "NAME")
+		cmd_direction := cmd.String("direction", "", "in|out")
+		cmd_status := cmd.String("status", "", "pending|success|reverted|replaced|dropped")
+		cmd_since := cmd.String("since", "", "YYYY-MM-DD")
+		cmd_until := cmd.String("until", "", "YYYY-MM-DD")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		return cli.History(*cmd_name, *cmd_direction, *cmd_status, *cmd_since, *cmd_until)
+	case "token":
+		if len(args) < 2 {
+			return cli.unknown(args)
+		}
+		cmd := flag.NewFlagSet("token", flag.ContinueOnError)
+		cmd_address := cmd.String("address", "", "ADDRESS")
+		cmd_token := cmd.String("token", "", "SYMBOL|ADDRESS")
+		cmd_decimals := cmd.Int("decimals", -1, "DECIMALS, default read from the token")
+		if err := cmd.Parse(args[2:]); err != nil {
+			return usageError(err)
+		}
+		switch args[1] {
+		case "add":
+			return cli.AddToken(*cmd_address, *cmd_decimals)
+		case "list":
+			return cli.ListTokens()
+		case "remove":
+			return cli.RemoveToken(*cmd_token)
+		default:
+			return cli.unknown(args)
+		}
+	case "approvetoken":
+		cmd := flag.NewFlagSet("approvetoken", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
+		cmd_name := cmd.String("name", "", "NAME")
+		cmd_token := cmd.String("token", "", "SYMBOL|ADDRESS")
+		cmd_spender := cmd.String("spender", "", "SPENDER")
+		cmd_value := cmd.String("value", "0", "VALUE, in token units e.g. 1.5")
+		cmd_safe := cmd.Bool("safe", false, "RESET ALLOWANCE TO ZERO FIRST")
+		cmd_wait := addWaitFlags(cmd)
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
+		}
+		return cli.ApproveToken(pass, *cmd_name, *cmd_token, *cmd_spender, *cmd_value, *cmd_safe, cmd_wait.opts())
+	case "allowancetoken":
+		cmd := flag.NewFlagSet("allowancetoken", flag.ContinueOnError)
+		cmd_name := cmd.String("name", "", "NAME")
+		cmd_token := cmd.String("token", "", "SYMBOL|ADDRESS")
+		cmd_spender := cmd.String("spender", "", "SPENDER")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		return cli.AllowanceToken(*cmd_name, *cmd_token, *cmd_spender)
+	case "transferfromtoken":
+		cmd := flag.NewFlagSet("transferfromtoken", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
 		cmd_name := cmd.String("name", "", "NAME")
-		if err := cmd.Parse(os.Args[2:]); err != nil {
-			log.Fatal("Args Error")
+		cmd_token := cmd.String("token", "", "SYMBOL|ADDRESS")
+		cmd_from := cmd.String("from", "", "OWNER")
+		cmd_to := cmd.String("to", "", "TOADDR")
+		cmd_value := cmd.String("value", "0", "VALUE, in token units e.g. 1.5")
+		cmd_wait := addWaitFlags(cmd)
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
+		}
+		return cli.TransferFromToken(pass, *cmd_name, *cmd_token, *cmd_from, *cmd_to, *cmd_value, cmd_wait.opts())
+	case "tokenhistory":
+		cmd := flag.NewFlagSet("tokenhistory", flag.ContinueOnError)
+		cmd_name := cmd.String("name", "", "NAME")
+		cmd_token := cmd.String("token", "", "SYMBOL|ADDRESS")
+		cmd_start := cmd.Uint64("start", 0, "START BLOCK")
+		cmd_chunk := cmd.Uint64("chunk", 2000, "BLOCKS PER LOG QUERY")
+		cmd_confirmations := cmd.Uint64("confirmations", mytoken.SCAN_CONFIRMATIONS, "CONFIRMATIONS BEFORE STORING SCANNED BLOCKS")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		return cli.TokenHistory(*cmd_name, *cmd_token, *cmd_start, *cmd_chunk, *cmd_confirmations)
+	case "watch":
+		cmd := flag.NewFlagSet("watch", flag.ContinueOnError)
+		cmd_confirmations := cmd.Uint64("confirmations", 3, "CONFIRMATIONS BEFORE REPORTING")
+		cmd_interval := cmd.Duration("interval", 5*time.Second, "POLL INTERVAL WITHOUT WEBSOCKET")
+		cmd_json := cmd.Bool("json", false, "PRINT JSON LINES")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
 		}
-		cli.GetBalance(*cmd_pass, *cmd_name)
+		return cli.Watch(*cmd_confirmations, *cmd_interval, *cmd_json || cli.out.json())
 	case "deploytoken":
-		cmd := flag.NewFlagSet("deploytoken", flag.ExitOnError)
-		cmd_pass := cmd.String("pass", "", "PASSWORD")
+		cmd := flag.NewFlagSet("deploytoken", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
 		cmd_name := cmd.String("name", "", "NAME")
-		if err := cmd.Parse(os.Args[2:]); err != nil {
-			log.Fatal("Args Error")
+		cmd_wait := addWaitFlags(cmd)
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
 		}
-		cli.DeployToken(*cmd_pass, *cmd_name)
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
+		}
+		return cli.DeployToken(pass, *cmd_name, cmd_wait.opts())
 	case "sendtoken":
-		cmd := flag.NewFlagSet("sendtoken", flag.ExitOnError)
-		cmd_pass := cmd.String("pass", "", "PASSWORD")
+		cmd := flag.NewFlagSet("sendtoken", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
 		cmd_name := cmd.String("name", "", "NAME")
 		cmd_to := cmd.String("to", "", "TOADDR")
-		cmd_value := cmd.Int64("value", 0, "VALUE")
-		if err := cmd.Parse(os.Args[2:]); err != nil {
-			log.Fatal("Args Error")
+		cmd_value := cmd.String("value", "0", "VALUE, in token units e.g. 1.5")
+		cmd_token := cmd.String("token", "", "SYMBOL|ADDRESS")
+		cmd_wait := addWaitFlags(cmd)
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
+		}
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
 		}
-		cli.SendToken(*cmd_pass, *cmd_name, *cmd_to, *cmd_value)
+		return cli.SendToken(pass, *cmd_name, *cmd_token, *cmd_to, *cmd_value, cmd_wait.opts())
 	case "minttoken":
-		cmd := flag.NewFlagSet("transfer", flag.ExitOnError)
-		cmd_pass := cmd.String("pass", "", "PASSWORD")
+		cmd := flag.NewFlagSet("transfer", flag.ContinueOnError)
+		cmd_pass := addPassFlags(cmd)
 		cmd_name := cmd.String("name", "", "NAME")
 		cmd_to := cmd.String("to", "", "TOADDR")
-		cmd_value := cmd.Int64("value", 0, "VALUE")
-		if err := cmd.Parse(os.Args[2:]); err != nil {
-			log.Fatal("Args Error")
+		cmd_value := cmd.String("value", "0", "VALUE, in token units e.g. 1.5")
+		cmd_wait := addWaitFlags(cmd)
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
 		}
-		cli.MintToken(*cmd_pass, *cmd_name, *cmd_to, *cmd_value)
+		pass, err := cmd_pass.get(false)
+		if err != nil {
+			return err
+		}
+		return cli.MintToken(pass, *cmd_name, *cmd_to, *cmd_value, cmd_wait.opts())
 	case "balancetoken":
-		cmd := flag.NewFlagSet("balancetoken", flag.ExitOnError)
+		cmd := flag.NewFlagSet("balancetoken", flag.ContinueOnError)
 		cmd_name := cmd.String("name", "", "NAME")
-		if err := cmd.Parse(os.Args[2:]); err != nil {
-			log.Fatal("Args Error")
+		cmd_token := cmd.String("token", "", "SYMBOL|ADDRESS")
+		if err := cmd.Parse(args[1:]); err != nil {
+			return usageError(err)
 		}
-		cli.BalanceToken(*cmd_name)
+		return cli.BalanceToken(*cmd_name, *cmd_token)
 	default:
-		cli.Help()
+		return cli.unknown(args)
 	}
-	fmt.Println()
-	fmt.Println("==================================")
 }
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/qxoo/mywallet/config"
+	"github.com/qxoo/mywallet/db"
+	"github.com/qxoo/mywallet/wallet"
+)
+
+const (
+	ExitOK = iota
+	ExitError
+	ExitUsage
+	ExitWalletNotFound
+	ExitWrongPassword
+	ExitInsufficientFunds
+	ExitRPCUnreachable
+	ExitNameExists
+	ExitReverted
+	ExitConfig
+	ExitChainMismatch
+)
+
+var exitErrors = []struct {
+	err     error
+	code    int
+	message string
+}{
+	{db.ErrWalletNotFound, ExitWalletNotFound, "Check the name with show"},
+	{wallet.ErrKeyNotFound, ExitWalletNotFound, "The keystore in data_dir has no key for this wallet"},
+	{wallet.ErrWrongPassword, ExitWrongPassword, "Check the wallet password"},
+	{db.ErrMnemonicDecrypt, ExitWrongPassword, "Check the wallet password"},
+	{wallet.ErrInsufficientFunds, ExitInsufficientFunds, "Check the balance covers value and fee"},
+	{wallet.ErrRPCUnreachable, ExitRPCUnreachable, "Check eth_url in the config or MYWALLET_ETH_URL"},
+	{db.ErrNameExists, ExitNameExists, "Choose another -name"},
+	{wallet.ErrReverted, ExitReverted, "The transaction was mined but reverted"},
+	{wallet.ErrChainMismatch, ExitChainMismatch, "Check eth_url points at the chain_id of the network"},
+	{config.ErrInvalidConfig, ExitConfig, "Fix the config file or the MYWALLET_* environment"},
+}
+
+var ErrUsage = errors.New("Invalid arguments")
+
+func usageError(err error) error {
+	return fmt.Errorf("%w: %v", ErrUsage, err)
+}
+
+// ExitCode maps err to the process exit code and a hint for the user.
+func ExitCode(err error) (int, string) {
+	if err == nil {
+		return ExitOK, ""
+	}
+	if errors.Is(err, ErrUsage) {
+		return ExitUsage, "Run without arguments for help"
+	}
+	for _, e := range exitErrors {
+		if errors.Is(err, e.err) {
+			return e.code, e.message
+		}
+	}
+	return ExitError, ""
+}
+
+// Run executes the command line and returns the process exit code.
+func (cli CmdClient) Run() int {
+	global := flag.NewFlagSet("mywallet", flag.ContinueOnError)
+	global_output := global.String("output", OUTPUT_TEXT, "OUTPUT FORMAT, json|text")
+	global_network := global.String("network", "", "NETWORK PROFILE, default from config")
+	global_config := global.String("config", "", "CONFIG FILE PATH")
+	if err := global.Parse(os.Args[1:]); err != nil {
+		return ExitUsage
+	}
+	if err := cli.SetOutput(*global_output); err != nil {
+		log.Println(err)
+		return ExitUsage
+	}
+
+	cfg, err := config.Load(*global_config, *global_network)
+	if err != nil {
+		code, message := ExitCode(err)
+		if cli.out.json() {
+			cli.printDocument("config", err)
+		} else {
+			log.Println(err)
+			log.Println(message)
+		}
+		return code
+	}
+	config.Config = *cfg
+	if cli.Url == "" {
+		cli.Url = cfg.EthUrl
+	}
+	if cli.Path == "" {
+		cli.Path = cfg.DataDir
+	}
+
+	args := global.Args()
+	if len(args) < 1 {
+		cli.Help()
+		return ExitOK
+	}
+	if cli.out.json() {
+		command := args[0]
+		if (command == "contact" || command == "token") && len(args) > 1 {
+			command += " " + args[1]
+		}
+		err = cli.Exec(args)
+		cli.printDocument(command, err)
+		code, _ := ExitCode(err)
+		return code
+	}
+
+	fmt.Println("==================================")
+	fmt.Println()
+	err = cli.Exec(args)
+	if err != nil {
+		log.Println(err)
+	}
+	code, message := ExitCode(err)
+	if message != "" {
+		log.Println(message)
+	}
+	fmt.Println()
+	fmt.Println("==================================")
+	return code
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"syscall"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/qxoo/mywallet/config"
+	"github.com/qxoo/mywallet/db"
+	"github.com/qxoo/mywallet/wallet"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "none", err: nil, code: ExitOK},
+		{name: "other", err: errors.New("boom"), code: ExitError},
+		{name: "usage", err: usageError(errors.New("flag provided but not defined: -x")), code: ExitUsage},
+		{name: "wallet not found", err: fmt.Errorf("Load error: %w", db.ErrWalletNotFound), code: ExitWalletNotFound},
+		{name: "key not found", err: wallet.Classify(keystore.ErrNoMatch), code: ExitWalletNotFound},
+		{name: "wrong password", err: fmt.Errorf("Load Wallet error: %w", wallet.Classify(keystore.ErrDecrypt)), code: ExitWrongPassword},
+		{name: "wrong mnemonic password", err: db.ErrMnemonicDecrypt, code: ExitWrongPassword},
+		{name: "insufficient funds", err: wallet.Classify(errors.New("insufficient funds for gas * price + value")), code: ExitInsufficientFunds},
+		{name: "connection refused", err: wallet.Classify(syscall.ECONNREFUSED), code: ExitRPCUnreachable},
+		{name: "url error", err: wallet.Classify(&url.Error{Op: "Post", URL: "http://node", Err: errors.New("no such host")}), code: ExitRPCUnreachable},
+		{name: "name exists", err: db.ErrNameExists, code: ExitNameExists},
+		{name: "reverted", err: fmt.Errorf("%w: 0x01", wallet.ErrReverted), code: ExitReverted},
+		{name: "config", err: fmt.Errorf("%w: eth_url is required", config.ErrInvalidConfig), code: ExitConfig},
+		{name: "chain mismatch", err: fmt.Errorf("Broadcast error: %w", wallet.ErrChainMismatch), code: ExitChainMismatch},
+	}
+	for _, tt := range tests {
+		code, hint := ExitCode(tt.err)
+		if code != tt.code {
+			t.Errorf("%s: exit code %d, want %d", tt.name, code, tt.code)
+		}
+		if (hint == "") != (code == ExitOK || code == ExitError) {
+			t.Errorf("%s: hint %q for exit code %d", tt.name, hint, code)
+		}
+	}
+}
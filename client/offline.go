@@ -0,0 +1,367 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/qxoo/mywallet/db"
+	"github.com/qxoo/mywallet/mytoken"
+	"github.com/qxoo/mywallet/units"
+	"github.com/qxoo/mywallet/wallet"
+)
+
+const (
+	FORMAT_RAW  = "raw"
+	FORMAT_JSON = "json"
+)
+
+// SignResult is the envelope written by sign and where it was written.
+type SignResult struct {
+	wallet.SignedTx
+	File string `json:"file,omitempty"`
+}
+
+// getTxParams builds the signing parameters of sign from its flags, a
+// gas price signs a legacy transaction, a max fee a dynamic fee one.
+func getTxParams(nonce int64, gas uint64, chainid uint64, gasprice, maxfee, tip string, data []byte) (wallet.TxParams, error) {
+	txparams := wallet.TxParams{}
+	if nonce < 0 {
+		return txparams, fmt.Errorf("%w: sign needs -nonce", ErrUsage)
+	}
+	txparams.Nonce = uint64(nonce)
+
+	if gas == 0 {
+		if len(data) > 0 {
+			return txparams, fmt.Errorf("%w: sign needs -gas with -data", ErrUsage)
+		}
+		gas = params.TxGas
+	}
+	txparams.Gas = gas
+
+	if chainid == 0 {
+		return txparams, fmt.Errorf("%w: sign needs -chainid or chain_id in config", ErrUsage)
+	}
+	txparams.ChainID = new(big.Int).SetUint64(chainid)
+
+	var err error
+	switch {
+	case gasprice != "" && maxfee != "":
+		return txparams, fmt.Errorf("%w: sign takes -gasprice or -maxfee, not both", ErrUsage)
+	case gasprice != "":
+		if txparams.Fees.GasPrice, err = units.ParseUnit(gasprice, "gwei"); err != nil {
+			return txparams, fmt.Errorf("Invalid Gas Price: %w", err)
+		}
+	case maxfee != "":
+		if tip == "" {
+			return txparams, fmt.Errorf("%w: sign needs -tip with -maxfee", ErrUsage)
+		}
+		if txparams.Fees.GasFeeCap, err = units.ParseUnit(maxfee, "gwei"); err != nil {
+			return txparams, fmt.Errorf("Invalid Max Fee: %w", err)
+		}
+		if txparams.Fees.GasTipCap, err = units.ParseUnit(tip, "gwei"); err != nil {
+			return txparams, fmt.Errorf("Invalid Tip: %w", err)
+		}
+		if txparams.Fees.GasTipCap.Cmp(txparams.Fees.GasFeeCap) > 0 {
+			return txparams, fmt.Errorf("%w: -tip is above -maxfee", ErrUsage)
+		}
+	default:
+		return txparams, fmt.Errorf("%w: sign needs -gasprice or -maxfee", ErrUsage)
+	}
+	return txparams, nil
+}
+
+// SignTx signs a transfer without a node and writes it to file, or prints
+// it when file is empty.
+func (cli CmdClient) SignTx(pass string, name string, toaddr string, value string, data string, txparams wallet.TxParams, format string, file string) error {
+	if format != FORMAT_RAW && format != FORMAT_JSON {
+		return fmt.Errorf("%w: unknown format %s", ErrUsage, format)
+	}
+	amount, err := units.ParseUnit(value, "ether")
+	if err != nil {
+		return fmt.Errorf("Invalid Value: %w", err)
+	}
+	payload, err := getData(data)
+	if err != nil {
+		return fmt.Errorf("Invalid Data: %w", err)
+	}
+
+	toaddr, err = resolveAddress(cli.Path, toaddr)
+	if err != nil {
+		return err
+	}
+	addr, err := getAddressByName(cli.Path, name)
+	if err != nil {
+		return err
+	}
+
+	w, err := wallet.LoadWallet(cli.Path, pass, addr)
+	if err != nil {
+		return fmt.Errorf("Load Wallet error: %w", err)
+	}
+
+	tx, err := w.Sign(toaddr, amount, payload, txparams)
+	if err != nil {
+		return fmt.Errorf("Sign error: %w", err)
+	}
+	signed, err := wallet.NewSignedTx(tx)
+	if err != nil {
+		return fmt.Errorf("Sign error: %w", err)
+	}
+	return cli.writeSigned(signed, format, file)
+}
+
+func (cli CmdClient) writeSigned(signed *wallet.SignedTx, format string, file string) error {
+	content := signed.Raw + "\n"
+	if format == FORMAT_JSON {
+		encoded, err := json.MarshalIndent(signed, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Encode error: %w", err)
+		}
+		content = string(encoded) + "\n"
+	}
+	cli.setResult(SignResult{SignedTx: *signed, File: file})
+
+	if file == "" {
+		cli.printf("%s", content)
+		return nil
+	}
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		return fmt.Errorf("Write File error: %w", err)
+	}
+	value, _ := new(big.Int).SetString(signed.Value, 10)
+	cli.println("Transcation Address: ", signed.Hash)
+	cli.println("From: ", signed.From)
+	cli.println("To: ", signed.To)
+	cli.println("Value: ", units.FormatEther(value), "ETH")
+	cli.println("Nonce: ", signed.Nonce, " Chain ID: ", signed.ChainID)
+	cli.println("Signed transaction written to ", file)
+	return nil
+}
+
+// resolveSender accepts a wallet name or, on a machine without the
+// keystore, an address for -from.
+func resolveSender(dir, from string) (string, error) {
+	addr, err := getAddressByName(dir, from)
+	if err == nil || !errors.Is(err, db.ErrWalletNotFound) {
+		return addr, err
+	}
+	address, _, perr := wallet.ParseAddress(from)
+	if perr != nil {
+		return "", err
+	}
+	return address.Hex(), nil
+}
+
+// Prepare looks up nonce, fees and gas of a transfer, or of a token transfer
+// when tokenkey is set, and writes it unsigned for sign -file.
+func (cli CmdClient) Prepare(from string, tokenkey string, toaddr string, value string, data string, opts wallet.FeeOpts, file string) error {
+	toaddr, err := resolveAddress(cli.Path, toaddr)
+	if err != nil {
+		return err
+	}
+	addr, err := resolveSender(cli.Path, from)
+	if err != nil {
+		return err
+	}
+	payload, err := getData(data)
+	if err != nil {
+		return fmt.Errorf("Invalid Data: %w", err)
+	}
+
+	var unsigned *wallet.UnsignedTx
+	if tokenkey == "" {
+		amount, err := units.ParseUnit(value, "ether")
+		if err != nil {
+			return fmt.Errorf("Invalid Value: %w", err)
+		}
+		client, err := ethclient.Dial(cli.Url)
+		if err != nil {
+			return fmt.Errorf("Init EthClient error: %w", wallet.Classify(err))
+		}
+		defer client.Close()
+
+		txparams, err := wallet.Prepare(client, common.HexToAddress(addr), toaddr, amount, payload, opts)
+		if err != nil {
+			return fmt.Errorf("Prepare error: %w", err)
+		}
+		unsigned = wallet.NewUnsignedTx("transfer", common.HexToAddress(addr), toaddr, amount, payload, *txparams)
+	} else {
+		if len(payload) > 0 {
+			return fmt.Errorf("%w: prepare takes -data or -token, not both", ErrUsage)
+		}
+		token, registered, err := resolveToken(cli.Path, tokenkey)
+		if err != nil {
+			return err
+		}
+		mytoken_c, err := mytoken.NewTokenClient(cli.Url, token.Address)
+		if err != nil {
+			return fmt.Errorf("Load Token error: %w", err)
+		}
+		defer mytoken_c.Close()
+
+		if err := loadTokenDecimals(mytoken_c, &token, registered); err != nil {
+			return err
+		}
+		amount, err := units.ParseDecimal(value, int(token.Decimals))
+		if err != nil {
+			return fmt.Errorf("Invalid Value: %w", err)
+		}
+		payload, err = mytoken.TransferData(toaddr, amount)
+		if err != nil {
+			return fmt.Errorf("Prepare error: %w", err)
+		}
+		txparams, err := wallet.Prepare(mytoken_c.Client(), common.HexToAddress(addr), token.Address, big.NewInt(0), payload, opts)
+		if err != nil {
+			return fmt.Errorf("Prepare error: %w", err)
+		}
+		unsigned = wallet.NewUnsignedTx("sendtoken", common.HexToAddress(addr), token.Address, big.NewInt(0), payload, *txparams)
+		unsigned.Token = token.Address
+		unsigned.Symbol = tokenSymbol(token)
+		unsigned.Recipient = common.HexToAddress(toaddr).Hex()
+		unsigned.Amount = units.Format(amount, int(token.Decimals))
+	}
+
+	encoded, err := json.MarshalIndent(unsigned, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Encode error: %w", err)
+	}
+	cli.setResult(unsigned)
+	if file == "" {
+		cli.printf("%s\n", encoded)
+		return nil
+	}
+	if err := os.WriteFile(file, append(encoded, '\n'), 0600); err != nil {
+		return fmt.Errorf("Write File error: %w", err)
+	}
+	cli.println("From: ", unsigned.From)
+	cli.println("Nonce: ", unsigned.Nonce, " Gas: ", unsigned.Gas, " Chain ID: ", unsigned.ChainID)
+	cli.println("Unsigned transaction written to ", file)
+	return nil
+}
+
+// SignPrepared signs a transaction written by prepare after printing what
+// is signed, token transfers are shown as decoded from the call data.
+func (cli CmdClient) SignPrepared(pass string, prepared string, format string, file string) error {
+	if format != FORMAT_RAW && format != FORMAT_JSON {
+		return fmt.Errorf("%w: unknown format %s", ErrUsage, format)
+	}
+	content, err := os.ReadFile(prepared)
+	if err != nil {
+		return fmt.Errorf("Read File error: %w", err)
+	}
+	unsigned := wallet.UnsignedTx{}
+	if err := json.Unmarshal(content, &unsigned); err != nil {
+		return fmt.Errorf("Decode Transaction error: %w", err)
+	}
+	amount, payload, txparams, err := unsigned.Params()
+	if err != nil {
+		return fmt.Errorf("Decode Transaction error: %w", err)
+	}
+
+	cli.println("Kind: ", unsigned.Kind)
+	cli.println("From: ", unsigned.From)
+	cli.println("To: ", unsigned.To)
+	cli.println("Value: ", units.FormatEther(amount), "ETH")
+	if recipient, tokens, ok := mytoken.ParseTransferData(payload); ok {
+		if unsigned.Recipient != "" && !strings.EqualFold(unsigned.Recipient, recipient.Hex()) {
+			return fmt.Errorf("Decode Transaction error: recipient %s does not match call data %s", unsigned.Recipient, recipient.Hex())
+		}
+		cli.println("Token Recipient: ", recipient.Hex())
+		cli.println("Token Amount: ", tokens, "raw units,", unsigned.Amount, unsigned.Symbol)
+	} else if len(payload) > 0 {
+		cli.println("Data: ", unsigned.Data)
+	}
+	cli.println("Nonce: ", txparams.Nonce, " Gas: ", txparams.Gas, " Chain ID: ", txparams.ChainID)
+	cli.println("Fees: ", txparams.Fees.String())
+
+	w, err := wallet.LoadWallet(cli.Path, pass, unsigned.From)
+	if err != nil {
+		return fmt.Errorf("Load Wallet error: %w", err)
+	}
+	tx, err := w.Sign(unsigned.To, amount, payload, txparams)
+	if err != nil {
+		return fmt.Errorf("Sign error: %w", err)
+	}
+	signed, err := wallet.NewSignedTx(tx)
+	if err != nil {
+		return fmt.Errorf("Sign error: %w", err)
+	}
+	return cli.writeSigned(signed, format, file)
+}
+
+// Broadcast sends a transaction signed by sign and records it in history.
+func (cli CmdClient) Broadcast(file string, wait *wallet.WaitOpts) error {
+	if file == "" {
+		return fmt.Errorf("%w: broadcast needs -file", ErrUsage)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("Read File error: %w", err)
+	}
+	tx, err := wallet.DecodeTx(content)
+	if err != nil {
+		return fmt.Errorf("Decode Transaction error: %w", err)
+	}
+	from, err := wallet.TxSender(tx)
+	if err != nil {
+		return fmt.Errorf("Decode Transaction error: %w", err)
+	}
+
+	client, err := ethclient.Dial(cli.Url)
+	if err != nil {
+		return fmt.Errorf("Init EthClient error: %w", wallet.Classify(err))
+	}
+	defer client.Close()
+
+	chainid, err := wallet.ChainID(client)
+	if err != nil {
+		return fmt.Errorf("Broadcast error: %w", err)
+	}
+	if chainid.Cmp(tx.ChainId()) != 0 {
+		return fmt.Errorf("Broadcast error: %w: transaction is signed for chain id %s, node reports %s", wallet.ErrChainMismatch, tx.ChainId(), chainid)
+	}
+
+	if err := client.SendTransaction(context.Background(), tx); err != nil {
+		return fmt.Errorf("Broadcast error: %w", wallet.Classify(err))
+	}
+
+	to := ""
+	if tx.To() != nil {
+		to = tx.To().Hex()
+	}
+	record := db.Transaction{
+		Kind:  "transfer",
+		From:  from.Hex(),
+		To:    to,
+		Value: tx.Value().String(),
+	}
+	result := SendResult{
+		Kind: "transfer", From: from.Hex(), To: to,
+		Symbol: "ETH", Value: units.FormatEther(tx.Value()), Raw: tx.Value().String(),
+	}
+	// token transfers prepared by prepare are recorded like sendtoken
+	if recipient, amount, ok := mytoken.ParseTransferData(tx.Data()); ok && tx.Value().Sign() == 0 {
+		record.Token = to
+		record.To = recipient.Hex()
+		record.Value = amount.String()
+		token, _, err := resolveToken(cli.Path, to)
+		if err != nil {
+			token = db.Token{Address: to}
+		}
+		result = tokenSendResult("transfer", from.Hex(), recipient.Hex(), token, amount)
+	}
+	cli.saveTx(tx, record)
+	if err := cli.sendResult(result, client, tx, wait); err != nil {
+		return err
+	}
+	cli.println("Broadcast Success.")
+	return nil
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGetTxParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		nonce    int64
+		gas      uint64
+		chainid  uint64
+		gasprice string
+		maxfee   string
+		tip      string
+		data     []byte
+		wantgas  uint64
+		usage    bool
+		fail     string
+	}{
+		{name: "legacy", nonce: 3, chainid: 1337, gasprice: "20", wantgas: 21000},
+		{name: "dynamic", nonce: 0, gas: 50000, chainid: 1, maxfee: "30", tip: "1.5", wantgas: 50000},
+		{name: "data with gas", nonce: 1, gas: 60000, chainid: 1, gasprice: "1", data: []byte{1}, wantgas: 60000},
+		{name: "no nonce", nonce: -1, chainid: 1, gasprice: "1", usage: true},
+		{name: "data without gas", chainid: 1, gasprice: "1", data: []byte{1}, usage: true},
+		{name: "no chain id", gasprice: "1", usage: true},
+		{name: "no fees", chainid: 1, usage: true},
+		{name: "both fees", chainid: 1, gasprice: "1", maxfee: "2", tip: "1", usage: true},
+		{name: "no tip", chainid: 1, maxfee: "2", usage: true},
+		{name: "tip above fee", chainid: 1, maxfee: "2", tip: "3", usage: true},
+		{name: "bad gas price", chainid: 1, gasprice: "cheap", fail: "Invalid Gas Price"},
+		{name: "bad max fee", chainid: 1, maxfee: "x", tip: "1", fail: "Invalid Max Fee"},
+		{name: "bad tip", chainid: 1, maxfee: "2", tip: "x", fail: "Invalid Tip"},
+	}
+	for _, tt := range tests {
+		params, err := getTxParams(tt.nonce, tt.gas, tt.chainid, tt.gasprice, tt.maxfee, tt.tip, tt.data)
+		if tt.usage {
+			if !errors.Is(err, ErrUsage) {
+				t.Errorf("%s: error %v, want ErrUsage", tt.name, err)
+			}
+			continue
+		}
+		if tt.fail != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.fail) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.fail)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if params.Nonce != uint64(tt.nonce) || params.Gas != tt.wantgas || params.ChainID.Uint64() != tt.chainid {
+			t.Errorf("%s: nonce %d gas %d chain %s", tt.name, params.Nonce, params.Gas, params.ChainID)
+		}
+		if tt.gasprice != "" {
+			if params.Fees.Dynamic() || params.Fees.GasPrice.String() != tt.gasprice+"000000000" {
+				t.Errorf("%s: fees %s, want gas price %s gwei", tt.name, &params.Fees, tt.gasprice)
+			}
+		} else if !params.Fees.Dynamic() || params.Fees.GasFeeCap.String() != "30000000000" || params.Fees.GasTipCap.String() != "1500000000" {
+			t.Errorf("%s: fees %s, want max fee 30 gwei tip 1.5 gwei", tt.name, &params.Fees)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	OUTPUT_TEXT = "text"
+	OUTPUT_JSON = "json"
+)
+
+type output struct {
+	format string
+	result interface{}
+}
+
+func (out *output) json() bool {
+	return out != nil && out.format == OUTPUT_JSON
+}
+
+// Document is the single json document printed by a command with -output json.
+type Document struct {
+	Command string       `json:"command"`
+	Ok      bool         `json:"ok"`
+	Result  interface{}  `json:"result,omitempty"`
+	Error   *ErrorResult `json:"error,omitempty"`
+}
+
+type ErrorResult struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+type WalletResult struct {
+	Name     string `json:"name"`
+	Address  string `json:"address,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Mnemonic string `json:"mnemonic,omitempty"`
+}
+
+type AddressResult struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+type BalanceResult struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Token   string `json:"token,omitempty"`
+	Symbol  string `json:"symbol"`
+	Balance string `json:"balance"`
+	Raw     string `json:"raw"`
+}
+
+type AllowanceResult struct {
+	Owner     string `json:"owner"`
+	Spender   string `json:"spender"`
+	Token     string `json:"token"`
+	Symbol    string `json:"symbol"`
+	Allowance string `json:"allowance"`
+	Raw       string `json:"raw"`
+}
+
+// SendResult describes a sent transaction, Txs holds every transaction
+// of a safe approve.
+type SendResult struct {
+	Kind   string      `json:"kind"`
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+	Token  string      `json:"token,omitempty"`
+	Symbol string      `json:"symbol"`
+	Value  string      `json:"value"`
+	Raw    string      `json:"raw"`
+	Tx     *TxResult   `json:"tx,omitempty"`
+	Txs    []*TxResult `json:"txs,omitempty"`
+}
+
+type HistoryEntry struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	Direction  string    `json:"direction"`
+	Address    string    `json:"address"`
+	Value      string    `json:"value"`
+	Asset      string    `json:"asset"`
+	Status     string    `json:"status"`
+	Hash       string    `json:"hash"`
+	Replaces   string    `json:"replaces,omitempty"`
+	ReplacedBy string    `json:"replaced_by,omitempty"`
+}
+
+type HistoryResult struct {
+	Name    string         `json:"name"`
+	Address string         `json:"address"`
+	Txs     []HistoryEntry `json:"txs"`
+}
+
+type TokenHistoryEntry struct {
+	Block     uint64 `json:"block"`
+	Direction string `json:"direction"`
+	Address   string `json:"address"`
+	Value     string `json:"value"`
+	Balance   string `json:"balance"`
+	Hash      string `json:"hash"`
+}
+
+type TokenHistoryResult struct {
+	Name    string              `json:"name"`
+	Address string              `json:"address"`
+	Token   string              `json:"token"`
+	Symbol  string              `json:"symbol"`
+	Start   uint64              `json:"start"`
+	End     uint64              `json:"end"`
+	Opening string              `json:"opening"`
+	Events  []TokenHistoryEntry `json:"events"`
+}
+
+type BatchRow struct {
+	Row    int    `json:"row"`
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+	Token  string `json:"token,omitempty"`
+	Hash   string `json:"hash,omitempty"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchResult struct {
+	Name    string      `json:"name"`
+	From    string      `json:"from"`
+	File    string      `json:"file"`
+	Results string      `json:"results"`
+	Rows    []*BatchRow `json:"rows"`
+}
+
+type TxResult struct {
+	Hash    string `json:"hash"`
+	Status  string `json:"status"`
+	Block   uint64 `json:"block,omitempty"`
+	GasUsed uint64 `json:"gas_used,omitempty"`
+	Fee     string `json:"fee,omitempty"`
+	Url     string `json:"url,omitempty"`
+}
+
+func (cli *CmdClient) SetOutput(format string) error {
+	if format != OUTPUT_TEXT && format != OUTPUT_JSON {
+		return fmt.Errorf("%w: unknown output %s", ErrUsage, format)
+	}
+	if cli.out == nil {
+		cli.out = &output{}
+	}
+	cli.out.format = format
+	return nil
+}
+
+// println and printf only write in text output, json output is built from
+// the result set by the command.
+func (cli CmdClient) println(a ...interface{}) {
+	if !cli.out.json() {
+		fmt.Println(a...)
+	}
+}
+
+func (cli CmdClient) printf(format string, a ...interface{}) {
+	if !cli.out.json() {
+		fmt.Printf(format, a...)
+	}
+}
+
+func (cli CmdClient) setResult(result interface{}) {
+	if cli.out != nil {
+		cli.out.result = result
+	}
+}
+
+func (cli CmdClient) printDocument(command string, err error) {
+	doc := Document{Command: command, Ok: err == nil, Result: cli.out.result}
+	if err != nil {
+		code, hint := ExitCode(err)
+		doc.Error = &ErrorResult{Code: code, Message: err.Error(), Hint: hint}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		fmt.Fprintln(os.Stderr, "Encode error: ", err)
+	}
+}
+
+func addressList(data map[string]string) []AddressResult {
+	list := []AddressResult{}
+	for name, address := range data {
+		list = append(list, AddressResult{Name: name, Address: address})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
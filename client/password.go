@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	PASSWORD_ENV      = "MYWALLET_PASSWORD"
+	FROM_PASSWORD_ENV = "MYWALLET_FROM_PASSWORD"
+)
+
+type passFlags struct {
+	name   string
+	pass   *string
+	file   *string
+	env    string
+	prompt string
+}
+
+func addPassFlags(cmd *flag.FlagSet) passFlags {
+	return passFlags{
+		name:   "pass",
+		pass:   cmd.String("pass", "", "PASSWORD, visible in shell history, prefer -pass-file or the prompt"),
+		file:   cmd.String("pass-file", "", "FILE CONTAINING PASSWORD"),
+		env:    PASSWORD_ENV,
+		prompt: "Password",
+	}
+}
+
+// addFromPassFlags adds -from-pass and -from-pass-file for the password of
+// the -from wallet of a command that also sets a password of its own.
+func addFromPassFlags(cmd *flag.FlagSet) passFlags {
+	return passFlags{
+		name:   "from-pass",
+		pass:   cmd.String("from-pass", "", "PASSWORD OF -from, visible in shell history, prefer -from-pass-file or the prompt"),
+		file:   cmd.String("from-pass-file", "", "FILE CONTAINING PASSWORD OF -from"),
+		env:    FROM_PASSWORD_ENV,
+		prompt: "Password of -from",
+	}
+}
+
+func readPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("Read Password error: %w", err)
+	}
+	return string(pass), nil
+}
+
+// get returns the password from -pass, -pass-file, its environment variable
+// or a hidden prompt when stdin is a terminal, in that order. confirm asks twice
+// when prompting for a new password.
+func (f passFlags) get(confirm bool) (string, error) {
+	if *f.pass != "" {
+		log.Printf("Warning: -%s is visible in shell history and process list\n", f.name)
+		return *f.pass, nil
+	}
+	if *f.file != "" {
+		data, err := os.ReadFile(*f.file)
+		if err != nil {
+			return "", fmt.Errorf("Read Password File error: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if pass, ok := os.LookupEnv(f.env); ok {
+		return pass, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("%w: password required, use -%s-file or %s", ErrUsage, f.name, f.env)
+	}
+
+	pass, err := readPassword(f.prompt + ": ")
+	if err != nil || !confirm {
+		return pass, err
+	}
+	repeat, err := readPassword("Repeat " + f.prompt + ": ")
+	if err != nil {
+		return "", err
+	}
+	if repeat != pass {
+		return "", fmt.Errorf("%w: passwords do not match", ErrUsage)
+	}
+	return pass, nil
+}
+
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s Type yes to continue: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	return strings.TrimSpace(answer) == "yes"
+}
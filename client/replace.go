@@ -0,0 +1,101 @@
+package client
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/qxoo/mywallet/db"
+	"github.com/qxoo/mywallet/units"
+	"github.com/qxoo/mywallet/wallet"
+)
+
+// Replace speeds up or cancels the pending transaction hash from history,
+// a hash that was replaced already is followed to its latest replacement.
+func (cli CmdClient) Replace(pass string, hash string, cancel bool, opts wallet.FeeOpts, wait *wallet.WaitOpts) error {
+	if hash == "" {
+		return fmt.Errorf("%w: -hash is required", ErrUsage)
+	}
+
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	chain, err := mydb.TxChain(hash)
+	mydb.Close()
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w, only transactions in history can be replaced", err)
+	}
+	// a chain with a mined transaction is settled, whatever replaced it later
+	cli.refreshPending(chain)
+	record := &chain[len(chain)-1]
+	for i := range chain {
+		if chain[i].Status == db.TxSuccess || chain[i].Status == db.TxReverted {
+			record = &chain[i]
+		}
+	}
+	if record.Hash != hash && record.Status == db.TxPending {
+		cli.println("Replacing latest replacement ", record.Hash)
+	}
+	if record.Status != db.TxPending {
+		return fmt.Errorf("%w: %s is %s", wallet.ErrNotPending, record.Hash, record.Status)
+	}
+
+	w, err := wallet.LoadWallet(cli.Path, pass, record.From)
+	if err != nil {
+		return fmt.Errorf("Load Wallet error: %w", err)
+	}
+	err = w.InitEthClient(cli.Url)
+	if err != nil {
+		return fmt.Errorf("Init EthClient error: %w", err)
+	}
+	defer w.CloseClient()
+
+	old, tx, err := w.Replace(record.Hash, cancel, opts)
+	if err != nil {
+		return fmt.Errorf("Replace error: %w", err)
+	}
+	if tx.Type() == types.LegacyTxType {
+		cli.printf("Gas price: %s gwei -> %s gwei\n", units.FormatGwei(old.GasPrice()), units.FormatGwei(tx.GasPrice()))
+	} else if old.Type() == tx.Type() {
+		cli.printf("Fees: %s gwei -> %s gwei, tip %s gwei -> %s gwei\n",
+			units.FormatGwei(old.GasFeeCap()), units.FormatGwei(tx.GasFeeCap()),
+			units.FormatGwei(old.GasTipCap()), units.FormatGwei(tx.GasTipCap()))
+	}
+
+	replacement := *record
+	replacement.Hash = tx.Hash().Hex()
+	replacement.Nonce = tx.Nonce()
+	replacement.Status = db.TxPending
+	replacement.Timestamp = time.Now()
+	replacement.Block, replacement.Fee, replacement.ReplacedBy = 0, "", ""
+	if cancel {
+		replacement.Kind, replacement.To, replacement.Value, replacement.Token = "cancel", record.From, "0", ""
+	}
+	if mydb, err := getDB(cli.Path); err != nil {
+		log.Println("Save History error: ", err)
+	} else {
+		err = mydb.ReplaceTx(record.Hash, replacement)
+		mydb.Close()
+		if err != nil {
+			log.Println("Save History error: ", err)
+		}
+	}
+
+	value, _ := new(big.Int).SetString(replacement.Value, 10)
+	result := SendResult{
+		Kind: replacement.Kind, From: replacement.From, To: replacement.To,
+		Symbol: "ETH", Value: units.FormatEther(value), Raw: replacement.Value,
+	}
+	if replacement.Token != "" {
+		token, _, err := resolveToken(cli.Path, replacement.Token)
+		if err != nil {
+			token = db.Token{Address: replacement.Token}
+		}
+		result = tokenSendResult(replacement.Kind, replacement.From, replacement.To, token, value)
+	}
+	cli.println("Replaces: ", record.Hash)
+	return cli.sendResult(result, w.Client, tx, wait)
+}
@@ -0,0 +1,352 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/qxoo/mywallet/config"
+	"github.com/qxoo/mywallet/db"
+	"github.com/qxoo/mywallet/mytoken"
+	"github.com/qxoo/mywallet/units"
+	"github.com/qxoo/mywallet/wallet"
+)
+
+// Movement is one confirmed incoming or outgoing transfer of a watched wallet.
+type Movement struct {
+	Time      time.Time `json:"time"`
+	Block     uint64    `json:"block"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Direction string    `json:"direction"`
+	Asset     string    `json:"asset"`
+	Token     string    `json:"token,omitempty"`
+	Value     string    `json:"value"`
+	Other     string    `json:"other,omitempty"`
+	TxHash    string    `json:"tx_hash,omitempty"`
+}
+
+type watcher struct {
+	client        *ethclient.Client
+	names         map[common.Address]string
+	owners        []common.Address
+	tokens        map[common.Address]db.Token
+	wallets       []*mytoken.TokenWallet
+	confirmations uint64
+	json          bool
+
+	balances map[common.Address]*big.Int
+	next     uint64
+	pending  map[string]mytoken.TransferEvent
+}
+
+func (w *watcher) emit(m Movement) {
+	if w.json {
+		line, err := json.Marshal(m)
+		if err != nil {
+			log.Println("Encode error: ", err)
+			return
+		}
+		fmt.Println(string(line))
+		return
+	}
+	fmt.Printf("%s \t%d \t%s \t%s \t%s %s \t%s \t%s\n",
+		m.Time.Format("2006-01-02 15:04:05"), m.Block, m.Name, m.Direction, m.Value, m.Asset, m.Other, m.TxHash)
+}
+
+func (w *watcher) emitTransfer(event mytoken.TransferEvent) {
+	token := w.tokens[event.Token]
+	value := units.Format(event.Value, int(token.Decimals))
+	if name, ok := w.names[event.From]; ok {
+		w.emit(Movement{
+			Time: time.Now(), Block: event.Block, Name: name, Address: event.From.Hex(),
+			Direction: "out", Asset: tokenSymbol(token), Token: token.Address,
+			Value: value, Other: event.To.Hex(), TxHash: event.TxHash.Hex(),
+		})
+	}
+	if name, ok := w.names[event.To]; ok {
+		w.emit(Movement{
+			Time: time.Now(), Block: event.Block, Name: name, Address: event.To.Hex(),
+			Direction: "in", Asset: tokenSymbol(token), Token: token.Address,
+			Value: value, Other: event.From.Hex(), TxHash: event.TxHash.Hex(),
+		})
+	}
+}
+
+func eventKey(event mytoken.TransferEvent) string {
+	return fmt.Sprintf("%s/%d", event.TxHash.Hex(), event.LogIndex)
+}
+
+// confirmed returns the newest block with enough confirmations at head.
+func (w *watcher) confirmed(head uint64) (uint64, bool) {
+	if head+1 < w.confirmations {
+		return 0, false
+	}
+	return head + 1 - w.confirmations, true
+}
+
+func (w *watcher) checkBalances(block uint64) error {
+	number := new(big.Int).SetUint64(block)
+	for _, owner := range w.owners {
+		balance, err := w.client.BalanceAt(context.Background(), owner, number)
+		if err != nil {
+			return err
+		}
+		prev, ok := w.balances[owner]
+		w.balances[owner] = balance
+		if !ok || prev.Cmp(balance) == 0 {
+			continue
+		}
+
+		delta := new(big.Int).Sub(balance, prev)
+		direction := "in"
+		if delta.Sign() < 0 {
+			direction = "out"
+			delta.Neg(delta)
+		}
+		w.emit(Movement{
+			Time: time.Now(), Block: block, Name: w.names[owner], Address: owner.Hex(),
+			Direction: direction, Asset: "ETH", Value: units.FormatEther(delta),
+		})
+	}
+	return nil
+}
+
+// checkPending re-reads the receipt of subscribed logs once they are
+// confirmed and drops the ones a reorg moved out of their block.
+func (w *watcher) checkPending(block uint64) {
+	for key, event := range w.pending {
+		if event.Block > block {
+			continue
+		}
+		delete(w.pending, key)
+
+		receipt, err := w.client.TransactionReceipt(context.Background(), event.TxHash)
+		if err != nil || receipt.BlockHash != event.BlockHash {
+			continue
+		}
+		w.emitTransfer(event)
+	}
+}
+
+// scanTransfers passes the Transfer logs of the watched wallets between
+// start and end to fn, once each.
+func (w *watcher) scanTransfers(start, end uint64, fn func(event mytoken.TransferEvent)) error {
+	for _, tw := range w.wallets {
+		// a transfer between two watched wallets is found by both scans
+		seen := map[string]bool{}
+		for _, owner := range w.owners {
+			err := tw.ScanTransfers(owner.Hex(), start, end, 2000, func(_, _ uint64, events []mytoken.TransferEvent) error {
+				for _, event := range events {
+					if seen[eventKey(event)] {
+						continue
+					}
+					seen[eventKey(event)] = true
+					fn(event)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// onHead handles every block that reached the required confirmations.
+func (w *watcher) onHead(head uint64, subscribed bool) error {
+	block, ok := w.confirmed(head)
+	if !ok || block < w.next {
+		return nil
+	}
+
+	if subscribed {
+		w.checkPending(block)
+	} else if err := w.scanTransfers(w.next, block, w.emitTransfer); err != nil {
+		return err
+	}
+	if err := w.checkBalances(block); err != nil {
+		return err
+	}
+	w.next = block + 1
+	return nil
+}
+
+func (cli CmdClient) Watch(confirmations uint64, interval time.Duration, jsonOutput bool) error {
+	if confirmations == 0 {
+		confirmations = 1
+	}
+
+	mydb, err := getDB(cli.Path)
+	if err != nil {
+		return err
+	}
+	wallets, err := mydb.GetAll()
+	if err != nil {
+		mydb.Close()
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+	tokens, err := mydb.Tokens()
+	mydb.Close()
+	if err != nil {
+		return fmt.Errorf("Query DB error: %w", err)
+	}
+
+	client, err := ethclient.Dial(cli.Url)
+	if err != nil {
+		return fmt.Errorf("Init EthClient error: %w", wallet.Classify(err))
+	}
+	defer client.Close()
+
+	w := &watcher{
+		client:        client,
+		names:         map[common.Address]string{},
+		tokens:        map[common.Address]db.Token{},
+		confirmations: confirmations,
+		json:          jsonOutput,
+		balances:      map[common.Address]*big.Int{},
+		pending:       map[string]mytoken.TransferEvent{},
+	}
+	for name, addr := range wallets {
+		address := common.HexToAddress(addr)
+		w.names[address] = name
+		w.owners = append(w.owners, address)
+	}
+
+	// the token at mytoken_address is watched even when not registered
+	var default_token db.Token
+	registered := true
+	if config.Config.MytokenAddress != "" {
+		if default_token, registered, err = resolveToken(cli.Path, ""); err != nil {
+			return err
+		}
+		listed := false
+		for _, token := range tokens {
+			listed = listed || token.Address == default_token.Address
+		}
+		if !listed {
+			tokens = append(tokens, default_token)
+		}
+	}
+	for _, token := range tokens {
+		tw, err := mytoken.NewTokenClient(cli.Url, token.Address)
+		if err != nil {
+			return fmt.Errorf("Load Token Wallet error: %w", err)
+		}
+		defer tw.Close()
+		if token.Address == default_token.Address {
+			if err := loadTokenDecimals(tw, &token, registered); err != nil {
+				return err
+			}
+		}
+		w.tokens[common.HexToAddress(token.Address)] = token
+		w.wallets = append(w.wallets, tw)
+	}
+
+	head, err := client.BlockNumber(context.Background())
+	if err != nil {
+		return fmt.Errorf("Get Block Number error: %w", wallet.Classify(err))
+	}
+	start, _ := w.confirmed(head)
+	if err := w.checkBalances(start); err != nil {
+		return fmt.Errorf("Get Balance error: %w", wallet.Classify(err))
+	}
+	w.next = start + 1
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	// prefer subscriptions, plain http endpoints fall back to polling
+	heads := make(chan *types.Header)
+	transfers := make(chan mytoken.TransferEvent)
+	subs := []event.Subscription{}
+	sub, err := client.SubscribeNewHead(context.Background(), heads)
+	subscribed := err == nil
+	defer func() {
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+	}()
+	if subscribed {
+		subs = append(subs, sub)
+		for _, tw := range w.wallets {
+			sub, err := tw.WatchTransfers(w.owners, transfers)
+			if err != nil {
+				return fmt.Errorf("Watch Transfer error: %w", err)
+			}
+			subs = append(subs, sub)
+		}
+
+		// subscriptions start at the head, logs mined since the start
+		// block are scanned and reported once confirmed like the others
+		head, err := client.BlockNumber(context.Background())
+		if err != nil {
+			return fmt.Errorf("Get Block Number error: %w", wallet.Classify(err))
+		}
+		if w.next <= head {
+			err = w.scanTransfers(w.next, head, func(event mytoken.TransferEvent) {
+				w.pending[eventKey(event)] = event
+			})
+			if err != nil {
+				return fmt.Errorf("Scan Transfer error: %w", wallet.Classify(err))
+			}
+		}
+	}
+	errs := make(chan error, len(subs))
+	for _, sub := range subs {
+		go func(sub event.Subscription) {
+			if err, ok := <-sub.Err(); ok {
+				errs <- err
+			}
+		}(sub)
+	}
+
+	mode := "polling"
+	if subscribed {
+		mode = "subscription"
+	}
+	log.Printf("Watching %d wallets and %d tokens from block %d by %s\n", len(w.owners), len(w.wallets), w.next, mode)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupt:
+			return nil
+		case err := <-errs:
+			return fmt.Errorf("Subscription error: %w", wallet.Classify(err))
+		case header := <-heads:
+			if err := w.onHead(header.Number.Uint64(), true); err != nil {
+				log.Println("Watch error: ", err)
+			}
+		case event := <-transfers:
+			if event.Removed {
+				delete(w.pending, eventKey(event))
+			} else {
+				w.pending[eventKey(event)] = event
+			}
+		case <-ticker.C:
+			if subscribed {
+				continue
+			}
+			head, err := client.BlockNumber(context.Background())
+			if err != nil {
+				log.Println("Get Block Number error: ", err)
+				continue
+			}
+			if err := w.onHead(head, false); err != nil {
+				log.Println("Watch error: ", err)
+			}
+		}
+	}
+}
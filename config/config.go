@@ -2,36 +2,217 @@ package config
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
+	"strconv"
+	"strings"
 )
 
-func init() {
-	_, path, _, _ := runtime.Caller(0)
-	root := filepath.Join(filepath.Dir(path), "..")
+const (
+	CONFIG_FILE = "config.json"
+	ENV_PREFIX  = "MYWALLET_"
+)
+
+var ErrInvalidConfig = errors.New("Invalid config")
 
-	Config.Root = root
-	cfg := filepath.Join(root, "config.json")
+// Network holds the settings of one chain, empty fields of a profile keep
+// the top-level value except token addresses, see UseNetwork.
+type Network struct {
+	EthUrl         string            `json:"eth_url"`
+	ChainID        uint64            `json:"chain_id"`
+	GasLimit       uint64            `json:"gas_limit"`
+	GasMultiplier  float64           `json:"gas_multiplier"`
+	MytokenAddress string            `json:"mytoken_address"`
+	Tokens         map[string]string `json:"tokens"`
+	MaxFee         string            `json:"max_fee"`
+	MaxTip         string            `json:"max_tip"`
+	ExplorerUrl    string            `json:"explorer_url"`
+}
 
-	fl, err := os.Open(cfg)
-	if err != nil {
-		log.Fatalln("Open Config file fail: ", err)
+type Configuration struct {
+	Network
+	DataDir  string             `json:"data_dir"`
+	Default  string             `json:"network"`
+	Networks map[string]Network `json:"networks"`
+	Name     string             `json:"-"`
+	Root     string             `json:"-"`
+}
+
+func env(name string) (string, bool) {
+	return os.LookupEnv(ENV_PREFIX + name)
+}
+
+// Paths returns the config files searched in order when -config is not given.
+func Paths() []string {
+	paths := []string{}
+	if path, ok := env("CONFIG"); ok {
+		paths = append(paths, path)
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "mywallet", CONFIG_FILE))
+	}
+	return append(paths, CONFIG_FILE)
+}
+
+// Load reads the config file at path, or the first of Paths when path is
+// empty, selects network, applies MYWALLET_* environment overrides and
+// validates the result. A relative data_dir is relative to the config file.
+func Load(path string, network string) (*Configuration, error) {
+	c := &Configuration{}
+	if path == "" {
+		for _, candidate := range Paths() {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
 	}
+	if path != "" {
+		fl, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("Open Config file fail: %w", err)
+		}
+		defer fl.Close()
 
-	err = json.NewDecoder(fl).Decode(&Config)
-	if err != nil {
-		log.Fatalln("Load Config file fail: ", err)
+		decoder := json.NewDecoder(fl)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(c); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+		}
+		if c.Root, err = filepath.Abs(filepath.Dir(path)); err != nil {
+			return nil, err
+		}
+	} else if _, ok := env("ETH_URL"); !ok {
+		return nil, fmt.Errorf("%w: no config file in %s", ErrInvalidConfig, strings.Join(Paths(), ", "))
 	}
+
+	if network == "" {
+		network, _ = env("NETWORK")
+	}
+	if err := c.UseNetwork(network); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if err := c.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if c.DataDir == "" {
+		c.DataDir = "datadir"
+	}
+	if !filepath.IsAbs(c.DataDir) && c.Root != "" {
+		c.DataDir = filepath.Join(c.Root, c.DataDir)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-type Configuration struct {
-	EthUrl         string `json:"eth_url"`
-	DataDir        string `json:"data_dir"`
-	GasLimit       uint64 `json:"gas_limit"`
-	MytokenAddress string `json:"mytoken_address"`
-	Root           string
+func (c *Configuration) applyEnv() error {
+	strs := map[string]*string{
+		"ETH_URL":         &c.EthUrl,
+		"DATA_DIR":        &c.DataDir,
+		"MYTOKEN_ADDRESS": &c.MytokenAddress,
+		"MAX_FEE":         &c.MaxFee,
+		"MAX_TIP":         &c.MaxTip,
+		"EXPLORER_URL":    &c.ExplorerUrl,
+	}
+	for name, field := range strs {
+		if value, ok := env(name); ok {
+			*field = value
+		}
+	}
+
+	uints := map[string]*uint64{
+		"CHAIN_ID":  &c.ChainID,
+		"GAS_LIMIT": &c.GasLimit,
+	}
+	for name, field := range uints {
+		if value, ok := env(name); ok {
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: %s%s: %v", ErrInvalidConfig, ENV_PREFIX, name, err)
+			}
+			*field = n
+		}
+	}
+
+	if value, ok := env("GAS_MULTIPLIER"); ok {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %sGAS_MULTIPLIER: %v", ErrInvalidConfig, ENV_PREFIX, err)
+		}
+		c.GasMultiplier = n
+	}
+	return nil
+}
+
+// UseNetwork applies the named profile over the top-level settings, an
+// empty name selects the default network if one is configured.
+func (c *Configuration) UseNetwork(name string) error {
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" {
+		return nil
+	}
+	profile, ok := c.Networks[name]
+	if !ok {
+		return fmt.Errorf("Unknown network: %s", name)
+	}
+
+	if profile.EthUrl != "" {
+		c.EthUrl = profile.EthUrl
+	}
+	if profile.ChainID != 0 {
+		c.ChainID = profile.ChainID
+	}
+	if profile.GasLimit != 0 {
+		c.GasLimit = profile.GasLimit
+	}
+	if profile.GasMultiplier != 0 {
+		c.GasMultiplier = profile.GasMultiplier
+	}
+	// contract addresses only hold on their own chain, a profile with a
+	// chain id of its own does not inherit the top-level ones
+	if profile.MytokenAddress != "" || profile.ChainID != 0 {
+		c.MytokenAddress = profile.MytokenAddress
+	}
+	if profile.Tokens != nil || profile.ChainID != 0 {
+		c.Tokens = profile.Tokens
+	}
+	if profile.MaxFee != "" {
+		c.MaxFee = profile.MaxFee
+	}
+	if profile.MaxTip != "" {
+		c.MaxTip = profile.MaxTip
+	}
+	if profile.ExplorerUrl != "" {
+		c.ExplorerUrl = profile.ExplorerUrl
+	}
+	c.Name = name
+	return nil
+}
+
+// Token returns the address of a token symbol configured for the network.
+func (c *Configuration) Token(symbol string) (string, bool) {
+	for key, address := range c.Tokens {
+		if strings.EqualFold(key, symbol) {
+			return address, true
+		}
+	}
+	return "", false
+}
+
+// TxUrl links hash on the block explorer of the network, if any.
+func (c *Configuration) TxUrl(hash string) string {
+	if c.ExplorerUrl == "" {
+		return ""
+	}
+	return strings.TrimRight(c.ExplorerUrl, "/") + "/tx/" + hash
 }
 
+// Config is the configuration in use, set from Load by the command line.
 var Config Configuration
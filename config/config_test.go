@@ -0,0 +1,66 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUseNetwork(t *testing.T) {
+	const top, local = "0x85D01b1309d8714E61Cd53981D6AB6a30307A7AA", "0x6B175474E89094C44Da98b954EedeAC495271d0F"
+	base := func() *Configuration {
+		return &Configuration{
+			Network: Network{
+				EthUrl: "http://localhost:8545", ChainID: 1337, MaxFee: "500gwei",
+				MytokenAddress: top, Tokens: map[string]string{"TOP": top},
+			},
+			Networks: map[string]Network{
+				"same":   {EthUrl: "http://other:8545"},
+				"local":  {ChainID: 1337, MytokenAddress: local},
+				"remote": {EthUrl: "https://rpc.example", ChainID: 1, MaxFee: "100gwei"},
+				"tokens": {ChainID: 5, Tokens: map[string]string{"LOC": local}},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		url     string
+		chain   uint64
+		maxfee  string
+		mytoken string
+		tokens  map[string]string
+		fail    bool
+	}{
+		{name: "", url: "http://localhost:8545", chain: 1337, maxfee: "500gwei", mytoken: top, tokens: map[string]string{"TOP": top}},
+		{name: "same", url: "http://other:8545", chain: 1337, maxfee: "500gwei", mytoken: top, tokens: map[string]string{"TOP": top}},
+		{name: "local", url: "http://localhost:8545", chain: 1337, maxfee: "500gwei", mytoken: local},
+		{name: "remote", url: "https://rpc.example", chain: 1, maxfee: "100gwei"},
+		{name: "tokens", url: "http://localhost:8545", chain: 5, maxfee: "500gwei", tokens: map[string]string{"LOC": local}},
+		{name: "missing", fail: true},
+	}
+	for _, tt := range tests {
+		c := base()
+		err := c.UseNetwork(tt.name)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("UseNetwork(%q) succeeded, want error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("UseNetwork(%q) error: %v", tt.name, err)
+			continue
+		}
+		if c.EthUrl != tt.url || c.ChainID != tt.chain || c.MaxFee != tt.maxfee {
+			t.Errorf("UseNetwork(%q) = %s chain %d max fee %s, want %s chain %d max fee %s",
+				tt.name, c.EthUrl, c.ChainID, c.MaxFee, tt.url, tt.chain, tt.maxfee)
+		}
+		if c.MytokenAddress != tt.mytoken {
+			t.Errorf("UseNetwork(%q) mytoken_address = %q, want %q", tt.name, c.MytokenAddress, tt.mytoken)
+		}
+		if len(c.Tokens) != 0 || len(tt.tokens) != 0 {
+			if !reflect.DeepEqual(c.Tokens, tt.tokens) {
+				t.Errorf("UseNetwork(%q) tokens = %v, want %v", tt.name, c.Tokens, tt.tokens)
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/qxoo/mywallet/units"
+)
+
+// Validate reports every invalid field at once.
+func (c *Configuration) Validate() error {
+	problems := []string{}
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	check(validateRPC(c.EthUrl))
+	if c.MytokenAddress != "" {
+		check(validateAddress("mytoken_address", c.MytokenAddress))
+	}
+	for symbol, address := range c.Tokens {
+		check(validateAddress("tokens."+symbol, address))
+	}
+	if c.GasLimit != 0 && c.GasLimit < params.TxGas {
+		check(fmt.Errorf("gas_limit %d is below the %d gas of a transfer", c.GasLimit, params.TxGas))
+	}
+	if c.GasMultiplier != 0 && (c.GasMultiplier < 1 || c.GasMultiplier > 10) {
+		check(fmt.Errorf("gas_multiplier %v must be between 1 and 10", c.GasMultiplier))
+	}
+	maxfee, err := validateFee("max_fee", c.MaxFee)
+	check(err)
+	maxtip, err := validateFee("max_tip", c.MaxTip)
+	check(err)
+	if maxfee != nil && maxtip != nil && maxtip.Cmp(maxfee) > 0 {
+		check(fmt.Errorf("max_tip %s is above max_fee %s", c.MaxTip, c.MaxFee))
+	}
+	if c.ExplorerUrl != "" {
+		if u, err := url.Parse(c.ExplorerUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			check(fmt.Errorf("explorer_url %q is not a http(s) url", c.ExplorerUrl))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateRPC accepts http, websocket and ipc endpoints like ethclient.Dial.
+func validateRPC(raw string) error {
+	if raw == "" {
+		return fmt.Errorf("eth_url is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("eth_url %q: %v", raw, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+		if u.Host == "" {
+			return fmt.Errorf("eth_url %q has no host", raw)
+		}
+	case "", "stdio":
+		// ipc path
+	default:
+		return fmt.Errorf("eth_url %q has unsupported scheme %s", raw, u.Scheme)
+	}
+	return nil
+}
+
+// validateAddress rejects malformed addresses and mixed-case addresses with
+// a wrong EIP-55 checksum.
+func validateAddress(field, address string) error {
+	if !common.IsHexAddress(address) {
+		return fmt.Errorf("%s %q is not an address", field, address)
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X")
+	if hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) {
+		if expected := common.HexToAddress(address).Hex(); expected != address {
+			return fmt.Errorf("%s %q has a wrong checksum, expected %s", field, address, expected)
+		}
+	}
+	return nil
+}
+
+func validateFee(field, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	fee, err := units.ParseUnit(value, "gwei")
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %v", field, value, err)
+	}
+	return fee, nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		network Network
+		want    []string
+	}{
+		{name: "minimal", network: Network{EthUrl: "http://localhost:8545"}},
+		{name: "full", network: Network{
+			EthUrl: "wss://node.example:8546", GasLimit: 21000, GasMultiplier: 1.2,
+			MytokenAddress: "0x85D01b1309d8714E61Cd53981D6AB6a30307A7AA",
+			Tokens:         map[string]string{"DAI": "0x6b175474e89094c44da98b954eedeac495271d0f"},
+			MaxFee:         "100gwei", MaxTip: "2", ExplorerUrl: "https://etherscan.io",
+		}},
+		{name: "ipc", network: Network{EthUrl: "/tmp/geth.ipc"}},
+		{name: "no url", network: Network{}, want: []string{"eth_url is required"}},
+		{name: "bad scheme", network: Network{EthUrl: "ftp://node"}, want: []string{"unsupported scheme ftp"}},
+		{name: "no host", network: Network{EthUrl: "http://"}, want: []string{"has no host"}},
+		{name: "bad address", network: Network{EthUrl: "http://node", MytokenAddress: "0x1234"},
+			want: []string{"mytoken_address \"0x1234\" is not an address"}},
+		{name: "bad checksum", network: Network{EthUrl: "http://node", MytokenAddress: "0x85d01b1309d8714E61Cd53981D6AB6a30307A7AA"},
+			want: []string{"wrong checksum, expected 0x85D01b1309d8714E61Cd53981D6AB6a30307A7AA"}},
+		{name: "bad token", network: Network{EthUrl: "http://node", Tokens: map[string]string{"USDC": "usdc"}},
+			want: []string{"tokens.USDC"}},
+		{name: "gas limit", network: Network{EthUrl: "http://node", GasLimit: 100}, want: []string{"gas_limit 100"}},
+		{name: "multiplier", network: Network{EthUrl: "http://node", GasMultiplier: 0.5}, want: []string{"gas_multiplier 0.5"}},
+		{name: "bad fee", network: Network{EthUrl: "http://node", MaxFee: "lots"}, want: []string{"max_fee \"lots\""}},
+		{name: "tip above fee", network: Network{EthUrl: "http://node", MaxFee: "1gwei", MaxTip: "2gwei"},
+			want: []string{"max_tip 2gwei is above max_fee 1gwei"}},
+		{name: "explorer", network: Network{EthUrl: "http://node", ExplorerUrl: "etherscan.io"}, want: []string{"explorer_url"}},
+		{name: "every problem", network: Network{GasLimit: 1, MaxTip: "x"},
+			want: []string{"eth_url is required", "gas_limit 1", "max_tip \"x\""}},
+	}
+	for _, tt := range tests {
+		c := &Configuration{Network: tt.network}
+		err := c.Validate()
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: error %v is not ErrInvalidConfig", tt.name, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q does not mention %q", tt.name, err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+const CONTACT_NAME = "Contact"
+
+func (cli *DB) SaveContact(name string, address string) error {
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(CONTACT_NAME))
+		return b.Put([]byte(name), []byte(address))
+	})
+}
+
+func (cli *DB) GetContact(name string) (address string, err error) {
+	err = cli.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(CONTACT_NAME))
+		v := b.Get([]byte(name))
+		if v == nil {
+			return fmt.Errorf("contact %s not exists", name)
+		}
+		address = string(v)
+		return nil
+	})
+	return
+}
+
+func (cli *DB) DeleteContact(name string) error {
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(CONTACT_NAME))
+		return b.Delete([]byte(name))
+	})
+}
+
+func (cli *DB) Contacts() (map[string]string, error) {
+	data := map[string]string{}
+	err := cli.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(CONTACT_NAME))
+		return b.ForEach(func(k, v []byte) error {
+			data[string(k)] = string(v)
+			return nil
+		})
+	})
+	return data, err
+}
@@ -1,28 +1,59 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"path"
+	"time"
 
 	"github.com/boltdb/bolt"
 )
 
 const DB_NAME = "MyWallet"
 
+// DB_OPEN_TIMEOUT is how long NewDB waits for another process holding the
+// database, e.g. a running watch.
+const DB_OPEN_TIMEOUT = 10 * time.Second
+
+var buckets = []string{
+	DB_NAME,
+	HD_NAME,
+	TX_NAME,
+	CONTACT_NAME,
+	TOKEN_NAME,
+	TOKEN_EVENT_NAME,
+	TOKEN_SCAN_NAME,
+	MNEMONIC_NAME,
+	NONCE_NAME,
+}
+
+var (
+	ErrWalletNotFound = errors.New("Wallet not found")
+	ErrNameExists     = errors.New("Name already exists")
+)
+
 type DB struct {
 	filename string
 	db       *bolt.DB
+	chain    uint64
 }
 
 func NewDB(dir string) (*DB, error) {
 	filename := path.Join(dir, "wallet.db")
-	db, err := bolt.Open(filename, 0600, nil)
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: DB_OPEN_TIMEOUT})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is in use by another process: %w", filename, err)
+	}
 	if err != nil {
 		return nil, err
 	}
-	db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(DB_NAME))
-		return err
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, err
@@ -30,6 +61,18 @@ func NewDB(dir string) (*DB, error) {
 	return &DB{filename: filename, db: db}, nil
 }
 
+// UseChain scopes transactions, tokens and token events to chainid, 0 leaves
+// them unscoped.
+func (cli *DB) UseChain(chainid uint64) {
+	cli.chain = chainid
+}
+
+// inChain reports whether a record of chainid belongs to the current chain,
+// records saved before network profiles have no chain id and belong to all.
+func (cli *DB) inChain(chainid uint64) bool {
+	return chainid == 0 || cli.chain == 0 || chainid == cli.chain
+}
+
 func (cli *DB) Exists(name string) (ret bool, err error) {
 	err = cli.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(DB_NAME))
@@ -49,7 +92,7 @@ func (cli *DB) GetAddress(name string) (address string, err error) {
 		b := tx.Bucket([]byte(DB_NAME))
 		v := b.Get([]byte(name))
 		if v == nil {
-			err = fmt.Errorf("key %s not exists", name)
+			err = fmt.Errorf("%w: %s", ErrWalletNotFound, name)
 		}
 		address = string(v)
 		return err
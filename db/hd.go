@@ -0,0 +1,64 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+const HD_NAME = "HDAccount"
+
+type HDAccount struct {
+	Seed string `json:"seed"`
+	Path string `json:"path"`
+}
+
+func (cli *DB) SaveHDAccount(name string, acc HDAccount) error {
+	val, err := json.Marshal(acc)
+	if err != nil {
+		return err
+	}
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(HD_NAME))
+		return b.Put([]byte(name), val)
+	})
+}
+
+func (cli *DB) GetHDAccount(name string) (acc *HDAccount, err error) {
+	err = cli.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(HD_NAME))
+		v := b.Get([]byte(name))
+		if v == nil {
+			return fmt.Errorf("hd account %s not exists", name)
+		}
+		acc = &HDAccount{}
+		return json.Unmarshal(v, acc)
+	})
+	return
+}
+
+func (cli *DB) DeleteHDAccount(name string) error {
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(HD_NAME))
+		return b.Delete([]byte(name))
+	})
+}
+
+func (cli *DB) HDPaths(seed string) ([]string, error) {
+	paths := []string{}
+	err := cli.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(HD_NAME))
+		return b.ForEach(func(k, v []byte) error {
+			acc := HDAccount{}
+			if err := json.Unmarshal(v, &acc); err != nil {
+				return err
+			}
+			if acc.Seed == seed {
+				paths = append(paths, acc.Path)
+			}
+			return nil
+		})
+	})
+	return paths, err
+}
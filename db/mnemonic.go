@@ -0,0 +1,128 @@
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"golang.org/x/crypto/scrypt"
+)
+
+const MNEMONIC_NAME = "Mnemonic"
+
+var ErrMnemonicDecrypt = errors.New("Could not decrypt mnemonic with given password")
+
+// same cost as the standard keystore encryption, tests lower scryptN
+var scryptN = 1 << 18
+
+const (
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// EncryptedMnemonic is the mnemonic sealed with AES-256-GCM under a key
+// derived from the wallet password by scrypt.
+type EncryptedMnemonic struct {
+	N      int    `json:"n"`
+	R      int    `json:"r"`
+	P      int    `json:"p"`
+	Salt   []byte `json:"salt"`
+	Nonce  []byte `json:"nonce"`
+	Cipher []byte `json:"cipher"`
+}
+
+func mnemonicCipher(pass string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(pass), salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func EncryptMnemonic(words string, pass string) (*EncryptedMnemonic, error) {
+	enc := &EncryptedMnemonic{N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, 32)}
+	if _, err := rand.Read(enc.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := mnemonicCipher(pass, enc.Salt, enc.N, enc.R, enc.P)
+	if err != nil {
+		return nil, err
+	}
+	enc.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(enc.Nonce); err != nil {
+		return nil, err
+	}
+	enc.Cipher = aead.Seal(nil, enc.Nonce, []byte(words), nil)
+	return enc, nil
+}
+
+func (enc *EncryptedMnemonic) Decrypt(pass string) (string, error) {
+	aead, err := mnemonicCipher(pass, enc.Salt, enc.N, enc.R, enc.P)
+	if err != nil {
+		return "", err
+	}
+	if len(enc.Nonce) != aead.NonceSize() {
+		return "", fmt.Errorf("invalid mnemonic nonce")
+	}
+	words, err := aead.Open(nil, enc.Nonce, enc.Cipher, nil)
+	if err != nil {
+		return "", ErrMnemonicDecrypt
+	}
+	return string(words), nil
+}
+
+func (cli *DB) SaveMnemonic(name string, words string, pass string) error {
+	enc, err := EncryptMnemonic(words, pass)
+	if err != nil {
+		return err
+	}
+	val, err := json.Marshal(enc)
+	if err != nil {
+		return err
+	}
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(MNEMONIC_NAME))
+		return b.Put([]byte(name), val)
+	})
+}
+
+func (cli *DB) GetMnemonic(name string, pass string) (string, error) {
+	enc := &EncryptedMnemonic{}
+	err := cli.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(MNEMONIC_NAME))
+		v := b.Get([]byte(name))
+		if v == nil {
+			return fmt.Errorf("mnemonic of %s not stored", name)
+		}
+		return json.Unmarshal(v, enc)
+	})
+	if err != nil {
+		return "", err
+	}
+	return enc.Decrypt(pass)
+}
+
+func (cli *DB) HasMnemonic(name string) (ret bool, err error) {
+	err = cli.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(MNEMONIC_NAME))
+		ret = b.Get([]byte(name)) != nil
+		return nil
+	})
+	return
+}
+
+func (cli *DB) DeleteMnemonic(name string) error {
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(MNEMONIC_NAME))
+		return b.Delete([]byte(name))
+	})
+}
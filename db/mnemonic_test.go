@@ -0,0 +1,62 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	mydb, err := NewDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mydb.Close() })
+	return mydb
+}
+
+func TestMnemonic(t *testing.T) {
+	defer func(n int) { scryptN = n }(scryptN)
+	scryptN = 1 << 10
+
+	const words = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	mydb := newTestDB(t)
+	if err := mydb.SaveMnemonic("a", words, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := mydb.GetMnemonic("a", "pw")
+	if err != nil || got != words {
+		t.Errorf("round trip: %q, %v, want %q", got, err, words)
+	}
+	if _, err := mydb.GetMnemonic("a", "wrong"); !errors.Is(err, ErrMnemonicDecrypt) {
+		t.Errorf("wrong password: error %v, want ErrMnemonicDecrypt", err)
+	}
+	if _, err := mydb.GetMnemonic("b", "pw"); err == nil {
+		t.Errorf("not stored: no error")
+	}
+
+	// flip a bit of the sealed words
+	err = mydb.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(MNEMONIC_NAME))
+		enc := EncryptedMnemonic{}
+		if err := json.Unmarshal(b.Get([]byte("a")), &enc); err != nil {
+			return err
+		}
+		enc.Cipher[0] ^= 1
+		val, err := json.Marshal(enc)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("a"), val)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mydb.GetMnemonic("a", "pw"); !errors.Is(err, ErrMnemonicDecrypt) {
+		t.Errorf("tampered cipher: error %v, want ErrMnemonicDecrypt", err)
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+const NONCE_NAME = "Nonce"
+
+// Nonce is the next nonce reserved for an account and when it was reserved.
+type Nonce struct {
+	Next    uint64    `json:"next"`
+	Updated time.Time `json:"updated"`
+}
+
+func (cli *DB) nonceKey(address string) []byte {
+	if cli.chain == 0 {
+		return []byte(address)
+	}
+	return []byte(fmt.Sprintf("%d/%s", cli.chain, address))
+}
+
+// UpdateNonce calls fn with the next nonce stored for address, zero and a
+// zero time when there is none, and stores the nonce it returns in the same
+// transaction, so concurrent senders never get the same nonce.
+func (cli *DB) UpdateNonce(address string, fn func(next uint64, updated time.Time) (uint64, error)) error {
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(NONCE_NAME))
+		key := cli.nonceKey(address)
+
+		nonce := Nonce{}
+		if v := b.Get(key); v != nil {
+			if err := json.Unmarshal(v, &nonce); err != nil {
+				return err
+			}
+		}
+		next, err := fn(nonce.Next, nonce.Updated)
+		if err != nil {
+			return err
+		}
+		if next == nonce.Next {
+			return nil
+		}
+
+		val, err := json.Marshal(Nonce{Next: next, Updated: time.Now()})
+		if err != nil {
+			return err
+		}
+		return b.Put(key, val)
+	})
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUpdateNonce(t *testing.T) {
+	const address = "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"
+	mydb := newTestDB(t)
+
+	// update stores what fn returns and reports what was stored before
+	update := func(next uint64) (stored uint64, updated time.Time) {
+		t.Helper()
+		err := mydb.UpdateNonce(address, func(n uint64, u time.Time) (uint64, error) {
+			stored, updated = n, u
+			return next, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	mydb.UseChain(1)
+	if stored, updated := update(5); stored != 0 || !updated.IsZero() {
+		t.Errorf("first use: %d at %v, want 0 and no time", stored, updated)
+	}
+	if stored, updated := update(6); stored != 5 || updated.IsZero() {
+		t.Errorf("chain 1: %d at %v, want 5", stored, updated)
+	}
+
+	mydb.UseChain(2)
+	if stored, _ := update(3); stored != 0 {
+		t.Errorf("chain 2: %d, want 0", stored)
+	}
+
+	mydb.UseChain(1)
+	failed := errors.New("failed")
+	err := mydb.UpdateNonce(address, func(n uint64, _ time.Time) (uint64, error) {
+		return n + 10, failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("error %v, want %v", err, failed)
+	}
+	if stored, _ := update(7); stored != 6 {
+		t.Errorf("chain 1 after failed update: %d, want 6", stored)
+	}
+	mydb.UseChain(2)
+	if stored, _ := update(4); stored != 3 {
+		t.Errorf("chain 2 again: %d, want 3", stored)
+	}
+}
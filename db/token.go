@@ -0,0 +1,90 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+const TOKEN_NAME = "Token"
+
+type Token struct {
+	Address  string `json:"address"`
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+	Decimals uint8  `json:"decimals"`
+	ChainID  uint64 `json:"chain_id,omitempty"`
+}
+
+func tokenKey(token Token) []byte {
+	if token.ChainID == 0 {
+		return []byte(token.Address)
+	}
+	return []byte(fmt.Sprintf("%d/%s", token.ChainID, token.Address))
+}
+
+func (cli *DB) SaveToken(token Token) error {
+	if token.ChainID == 0 {
+		token.ChainID = cli.chain
+	}
+	val, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TOKEN_NAME))
+		return b.Put(tokenKey(token), val)
+	})
+}
+
+func (cli *DB) DeleteToken(token Token) error {
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TOKEN_NAME))
+		return b.Delete(tokenKey(token))
+	})
+}
+
+func (cli *DB) Tokens() ([]Token, error) {
+	tokens := []Token{}
+	err := cli.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TOKEN_NAME))
+		return b.ForEach(func(k, v []byte) error {
+			token := Token{}
+			if err := json.Unmarshal(v, &token); err != nil {
+				return err
+			}
+			if cli.inChain(token.ChainID) {
+				tokens = append(tokens, token)
+			}
+			return nil
+		})
+	})
+	return tokens, err
+}
+
+// FindToken looks a registered token up by address or by symbol, a symbol
+// shared by several tokens is rejected as ambiguous.
+func (cli *DB) FindToken(key string) (*Token, error) {
+	tokens, err := cli.Tokens()
+	if err != nil {
+		return nil, err
+	}
+	var found *Token
+	for i, token := range tokens {
+		if strings.EqualFold(token.Address, key) {
+			return &tokens[i], nil
+		}
+		if strings.EqualFold(token.Symbol, key) {
+			if found != nil {
+				return nil, fmt.Errorf("token symbol %s is ambiguous, use the address", key)
+			}
+			found = &tokens[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("token %s not exists", key)
+	}
+	return found, nil
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	TOKEN_EVENT_NAME = "TokenEvent"
+	TOKEN_SCAN_NAME  = "TokenScan"
+)
+
+type TokenEvent struct {
+	Block    uint64 `json:"block"`
+	TxHash   string `json:"tx_hash"`
+	LogIndex uint   `json:"log_index"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Value    string `json:"value"`
+}
+
+// ScanRange is the block range [Start, Next) already scanned for Transfer logs.
+type ScanRange struct {
+	Start uint64 `json:"start"`
+	Next  uint64 `json:"next"`
+}
+
+func (cli *DB) scanKey(token, owner string) []byte {
+	if cli.chain == 0 {
+		return []byte(token + "/" + owner + "/")
+	}
+	return []byte(fmt.Sprintf("%d/%s/%s/", cli.chain, token, owner))
+}
+
+func (cli *DB) GetScanRange(token, owner string) (r *ScanRange, err error) {
+	err = cli.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TOKEN_SCAN_NAME))
+		v := b.Get(cli.scanKey(token, owner))
+		if v == nil {
+			return nil
+		}
+		r = &ScanRange{}
+		return json.Unmarshal(v, r)
+	})
+	return
+}
+
+// SaveTokenEvents stores the events of a scanned chunk together with the
+// new scanned range, so an interrupted scan resumes where it stopped.
+func (cli *DB) SaveTokenEvents(token, owner string, events []TokenEvent, r ScanRange) error {
+	rng, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TOKEN_EVENT_NAME))
+		for _, event := range events {
+			val, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			key := append(cli.scanKey(token, owner), []byte(fmt.Sprintf("%020d/%010d", event.Block, event.LogIndex))...)
+			if err := b.Put(key, val); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket([]byte(TOKEN_SCAN_NAME)).Put(cli.scanKey(token, owner), rng)
+	})
+}
+
+// TokenEvents returns the stored events of owner in chronological order.
+func (cli *DB) TokenEvents(token, owner string) ([]TokenEvent, error) {
+	events := []TokenEvent{}
+	prefix := cli.scanKey(token, owner)
+	err := cli.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(TOKEN_EVENT_NAME)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			event := TokenEvent{}
+			if err := json.Unmarshal(v, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	return events, err
+}
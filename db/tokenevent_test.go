@@ -0,0 +1,60 @@
+package db
+
+import "testing"
+
+func TestTokenEvents(t *testing.T) {
+	const token, owner = "0x85D01b1309d8714E61Cd53981D6AB6a30307A7AA", "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"
+	mydb := newTestDB(t)
+
+	rng, err := mydb.GetScanRange(token, owner)
+	if err != nil || rng != nil {
+		t.Fatalf("unscanned: %v, %v, want no range", rng, err)
+	}
+
+	// chunks arrive out of order and numbers of different length must still
+	// come back by block and log index
+	chunks := []struct {
+		events []TokenEvent
+		rng    ScanRange
+	}{
+		{events: []TokenEvent{{Block: 100, LogIndex: 2}, {Block: 100, LogIndex: 10}}, rng: ScanRange{Start: 90, Next: 101}},
+		{events: []TokenEvent{{Block: 9, LogIndex: 1}, {Block: 95, LogIndex: 0}}, rng: ScanRange{Start: 0, Next: 101}},
+		{events: nil, rng: ScanRange{Start: 0, Next: 200}},
+	}
+	for _, chunk := range chunks {
+		if err := mydb.SaveTokenEvents(token, owner, chunk.events, chunk.rng); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mydb.SaveTokenEvents(token, "0x000000000000000000000000000000000000dEaD", []TokenEvent{{Block: 50}}, ScanRange{Next: 60}); err != nil {
+		t.Fatal(err)
+	}
+
+	rng, err = mydb.GetScanRange(token, owner)
+	if err != nil || rng == nil || *rng != (ScanRange{Start: 0, Next: 200}) {
+		t.Errorf("range %v, %v, want 0-200", rng, err)
+	}
+
+	events, err := mydb.TokenEvents(token, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]uint64{{9, 1}, {95, 0}, {100, 2}, {100, 10}}
+	if len(events) != len(want) {
+		t.Fatalf("%d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.Block != want[i][0] || uint64(event.LogIndex) != want[i][1] {
+			t.Errorf("event %d at %d/%d, want %d/%d", i, event.Block, event.LogIndex, want[i][0], want[i][1])
+		}
+	}
+
+	// another chain has its own ranges and events
+	mydb.UseChain(5)
+	if rng, err := mydb.GetScanRange(token, owner); err != nil || rng != nil {
+		t.Errorf("other chain: range %v, %v, want none", rng, err)
+	}
+	if events, err := mydb.TokenEvents(token, owner); err != nil || len(events) != 0 {
+		t.Errorf("other chain: %d events, %v, want none", len(events), err)
+	}
+}
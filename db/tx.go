@@ -0,0 +1,201 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+const TX_NAME = "Transaction"
+
+const (
+	TxPending  = "pending"
+	TxSuccess  = "success"
+	TxReverted = "reverted"
+	TxReplaced = "replaced"
+	TxDropped  = "dropped"
+)
+
+type Transaction struct {
+	Hash      string    `json:"hash"`
+	Kind      string    `json:"kind"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Value     string    `json:"value"`
+	Token     string    `json:"token,omitempty"`
+	Nonce     uint64    `json:"nonce"`
+	Fee       string    `json:"fee,omitempty"`
+	Block     uint64    `json:"block,omitempty"`
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	ChainID   uint64    `json:"chain_id,omitempty"`
+	// Replaces and ReplacedBy link transactions sent with the same nonce by
+	// speedup and cancel
+	Replaces   string `json:"replaces,omitempty"`
+	ReplacedBy string `json:"replaced_by,omitempty"`
+}
+
+type TxFilter struct {
+	Address   string
+	Direction string
+	Status    string
+	Since     time.Time
+	Until     time.Time
+}
+
+func (f TxFilter) Match(tx Transaction) bool {
+	if f.Address != "" && tx.From != f.Address && tx.To != f.Address {
+		return false
+	}
+	if f.Direction == "out" && tx.From != f.Address {
+		return false
+	}
+	if f.Direction == "in" && tx.To != f.Address {
+		return false
+	}
+	if f.Status != "" && tx.Status != f.Status {
+		return false
+	}
+	if !f.Since.IsZero() && tx.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !tx.Timestamp.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+func (cli *DB) SaveTx(t Transaction) error {
+	if t.ChainID == 0 {
+		t.ChainID = cli.chain
+	}
+	val, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TX_NAME))
+		return b.Put([]byte(t.Hash), val)
+	})
+}
+
+func (cli *DB) GetTx(hash string) (t *Transaction, err error) {
+	err = cli.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TX_NAME))
+		v := b.Get([]byte(hash))
+		if v == nil {
+			return fmt.Errorf("transaction %s not exists", hash)
+		}
+		t = &Transaction{}
+		return json.Unmarshal(v, t)
+	})
+	return
+}
+
+func (cli *DB) UpdateTxStatus(hash string, status string, block uint64, fee string) error {
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TX_NAME))
+		v := b.Get([]byte(hash))
+		if v == nil {
+			return fmt.Errorf("transaction %s not exists", hash)
+		}
+		t := Transaction{}
+		if err := json.Unmarshal(v, &t); err != nil {
+			return err
+		}
+		t.Status, t.Block, t.Fee = status, block, fee
+		val, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(hash), val)
+	})
+}
+
+// ReplaceTx saves t as the replacement of the transaction old and marks
+// old as replaced.
+func (cli *DB) ReplaceTx(old string, t Transaction) error {
+	if t.ChainID == 0 {
+		t.ChainID = cli.chain
+	}
+	t.Replaces = old
+	return cli.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TX_NAME))
+		v := b.Get([]byte(old))
+		if v == nil {
+			return fmt.Errorf("transaction %s not exists", old)
+		}
+		replaced := Transaction{}
+		if err := json.Unmarshal(v, &replaced); err != nil {
+			return err
+		}
+		replaced.Status, replaced.ReplacedBy = TxReplaced, t.Hash
+		val, err := json.Marshal(replaced)
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte(old), val); err != nil {
+			return err
+		}
+
+		val, err = json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(t.Hash), val)
+	})
+}
+
+// LatestTx follows ReplacedBy from hash to the last replacement.
+func (cli *DB) LatestTx(hash string) (*Transaction, error) {
+	t, err := cli.GetTx(hash)
+	for err == nil && t.ReplacedBy != "" {
+		t, err = cli.GetTx(t.ReplacedBy)
+	}
+	return t, err
+}
+
+// TxChain returns the transactions hash belongs to, from the first one sent
+// to its latest replacement.
+func (cli *DB) TxChain(hash string) ([]Transaction, error) {
+	t, err := cli.GetTx(hash)
+	for err == nil && t.Replaces != "" {
+		t, err = cli.GetTx(t.Replaces)
+	}
+	if err != nil {
+		return nil, err
+	}
+	chain := []Transaction{*t}
+	for t.ReplacedBy != "" {
+		if t, err = cli.GetTx(t.ReplacedBy); err != nil {
+			return nil, err
+		}
+		chain = append(chain, *t)
+	}
+	return chain, nil
+}
+
+// Txs returns the transactions matching filter, oldest first.
+func (cli *DB) Txs(filter TxFilter) ([]Transaction, error) {
+	txs := []Transaction{}
+	err := cli.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TX_NAME))
+		return b.ForEach(func(k, v []byte) error {
+			t := Transaction{}
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			if cli.inChain(t.ChainID) && filter.Match(t) {
+				txs = append(txs, t)
+			}
+			return nil
+		})
+	})
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].Timestamp.Before(txs[j].Timestamp)
+	})
+	return txs, err
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTxFilter(t *testing.T) {
+	const a, b = "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", "0x000000000000000000000000000000000000dEaD"
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	sent := Transaction{Hash: "0x01", From: a, To: b, Status: TxSuccess, Timestamp: day.Add(12 * time.Hour)}
+	received := Transaction{Hash: "0x02", From: b, To: a, Status: TxPending, Timestamp: day.Add(36 * time.Hour)}
+	other := Transaction{Hash: "0x03", From: b, To: b, Status: TxSuccess, Timestamp: day}
+
+	tests := []struct {
+		name   string
+		filter TxFilter
+		want   []string
+	}{
+		{name: "all", filter: TxFilter{}, want: []string{"0x03", "0x01", "0x02"}},
+		{name: "address", filter: TxFilter{Address: a}, want: []string{"0x01", "0x02"}},
+		{name: "out", filter: TxFilter{Address: a, Direction: "out"}, want: []string{"0x01"}},
+		{name: "in", filter: TxFilter{Address: a, Direction: "in"}, want: []string{"0x02"}},
+		{name: "status", filter: TxFilter{Address: a, Status: TxPending}, want: []string{"0x02"}},
+		{name: "since", filter: TxFilter{Since: day.AddDate(0, 0, 1)}, want: []string{"0x02"}},
+		{name: "until", filter: TxFilter{Until: day.AddDate(0, 0, 1)}, want: []string{"0x03", "0x01"}},
+		{name: "until is exclusive", filter: TxFilter{Until: day}, want: []string{}},
+		{name: "day", filter: TxFilter{Since: day, Until: day.AddDate(0, 0, 1), Address: a}, want: []string{"0x01"}},
+	}
+
+	mydb := newTestDB(t)
+	for _, tx := range []Transaction{received, sent, other} {
+		if err := mydb.SaveTx(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range tests {
+		txs, err := mydb.Txs(tt.filter)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		got := []string{}
+		for _, tx := range txs {
+			got = append(got, tx.Hash)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestReplaceTx(t *testing.T) {
+	mydb := newTestDB(t)
+	if err := mydb.SaveTx(Transaction{Hash: "0x01", Nonce: 4, Status: TxPending, Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []string{"0x02", "0x03"} {
+		latest, err := mydb.LatestTx("0x01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mydb.ReplaceTx(latest.Hash, Transaction{Hash: hash, Nonce: 4, Status: TxPending, Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mydb.ReplaceTx("0x09", Transaction{Hash: "0x0a"}); err == nil {
+		t.Errorf("replacing unknown transaction: no error")
+	}
+
+	for _, hash := range []string{"0x01", "0x02", "0x03"} {
+		latest, err := mydb.LatestTx(hash)
+		if err != nil || latest.Hash != "0x03" {
+			t.Errorf("LatestTx(%s) = %v, %v, want 0x03", hash, latest, err)
+		}
+		chain, err := mydb.TxChain(hash)
+		if err != nil {
+			t.Errorf("TxChain(%s): unexpected error: %v", hash, err)
+			continue
+		}
+		want := []struct{ hash, status, replaces, replacedby string }{
+			{"0x01", TxReplaced, "", "0x02"},
+			{"0x02", TxReplaced, "0x01", "0x03"},
+			{"0x03", TxPending, "0x02", ""},
+		}
+		if len(chain) != len(want) {
+			t.Errorf("TxChain(%s): %d transactions, want %d", hash, len(chain), len(want))
+			continue
+		}
+		for i, tx := range chain {
+			if tx.Hash != want[i].hash || tx.Status != want[i].status || tx.Replaces != want[i].replaces || tx.ReplacedBy != want[i].replacedby {
+				t.Errorf("TxChain(%s)[%d] = %s %s %q %q, want %v", hash, i, tx.Hash, tx.Status, tx.Replaces, tx.ReplacedBy, want[i])
+			}
+		}
+	}
+}
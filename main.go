@@ -1,13 +1,12 @@
 package main
 
 import (
+	"os"
+
 	"github.com/qxoo/mywallet/client"
-	"github.com/qxoo/mywallet/config"
 )
 
-const PATH = "./datadir"
-
 func main() {
-	client := client.NewCmdClient(config.Config.EthUrl, config.Config.DataDir)
-	client.Run()
+	client := client.NewCmdClient("", "")
+	os.Exit(client.Run())
 }
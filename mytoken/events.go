@@ -0,0 +1,165 @@
+package mytoken
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/qxoo/mywallet/sol"
+	"github.com/qxoo/mywallet/wallet"
+)
+
+type TransferEvent struct {
+	Token     common.Address
+	Block     uint64
+	BlockHash common.Hash
+	TxHash    common.Hash
+	LogIndex  uint
+	From      common.Address
+	To        common.Address
+	Value     *big.Int
+	Removed   bool
+}
+
+func newTransferEvent(event *sol.IERC20Transfer) TransferEvent {
+	return TransferEvent{
+		Token:     event.Raw.Address,
+		Block:     event.Raw.BlockNumber,
+		BlockHash: event.Raw.BlockHash,
+		TxHash:    event.Raw.TxHash,
+		LogIndex:  event.Raw.Index,
+		From:      event.From,
+		To:        event.To,
+		Value:     event.Value,
+		Removed:   event.Raw.Removed,
+	}
+}
+
+func (tw *TokenWallet) BlockNumber() (uint64, error) {
+	number, err := tw.wallet.Client.BlockNumber(context.Background())
+	return number, wallet.Classify(err)
+}
+
+func (tw *TokenWallet) BalanceAt(owner string, block uint64) (*big.Int, error) {
+	instance, err := tw.getERC20()
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{BlockNumber: new(big.Int).SetUint64(block)}
+	return instance.BalanceOf(opts, common.HexToAddress(owner))
+}
+
+func (tw *TokenWallet) filterTransfers(instance *sol.IERC20, start, end uint64, from, to []common.Address) ([]TransferEvent, error) {
+	it, err := instance.FilterTransfer(&bind.FilterOpts{Start: start, End: &end}, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	events := []TransferEvent{}
+	for it.Next() {
+		events = append(events, newTransferEvent(it.Event))
+	}
+	return events, it.Error()
+}
+
+// SCAN_CONFIRMATIONS is how far below the head scanned logs are taken as
+// final, newer blocks may still be reorged.
+const SCAN_CONFIRMATIONS = 12
+
+// ScanTransfers reads the Transfer logs sent or received by owner between
+// start and end in chunks of at most chunk blocks, halving the chunk when
+// the node rejects a range and doubling it again after ranges it accepts.
+// fn is called after every chunk so the caller can store progress.
+func (tw *TokenWallet) ScanTransfers(owner string, start, end uint64, chunk uint64, fn func(start, end uint64, events []TransferEvent) error) error {
+	instance, err := tw.getERC20()
+	if err != nil {
+		return err
+	}
+	if chunk == 0 {
+		chunk = 1
+	}
+	max := chunk
+
+	account := []common.Address{common.HexToAddress(owner)}
+	for start <= end {
+		last := start + chunk - 1
+		if last > end {
+			last = end
+		}
+
+		sent, err := tw.filterTransfers(instance, start, last, account, nil)
+		if err == nil {
+			var received []TransferEvent
+			received, err = tw.filterTransfers(instance, start, last, nil, account)
+			sent = append(sent, received...)
+		}
+		if err != nil {
+			if chunk == 1 {
+				return err
+			}
+			chunk /= 2
+			continue
+		}
+
+		if err := fn(start, last, sent); err != nil {
+			return err
+		}
+		start = last + 1
+		if chunk < max {
+			chunk = minUint(chunk*2, max)
+		}
+	}
+	return nil
+}
+
+func minUint(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// WatchTransfers subscribes to the Transfer logs sent or received by owners,
+// it needs a websocket connection. Logs removed by a reorg are delivered
+// again with Removed set.
+func (tw *TokenWallet) WatchTransfers(owners []common.Address, sink chan<- TransferEvent) (event.Subscription, error) {
+	instance, err := tw.getERC20()
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make(chan *sol.IERC20Transfer)
+	sent, err := instance.WatchTransfer(&bind.WatchOpts{}, logs, owners, nil)
+	if err != nil {
+		return nil, err
+	}
+	received, err := instance.WatchTransfer(&bind.WatchOpts{}, logs, nil, owners)
+	if err != nil {
+		sent.Unsubscribe()
+		return nil, err
+	}
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sent.Unsubscribe()
+		defer received.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				select {
+				case sink <- newTransferEvent(log):
+				case <-quit:
+					return nil
+				}
+			case err := <-sent.Err():
+				return err
+			case err := <-received.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
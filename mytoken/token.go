@@ -3,115 +3,315 @@ package mytoken
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/qxoo/mywallet/config"
 	"github.com/qxoo/mywallet/sol"
 	"github.com/qxoo/mywallet/wallet"
 )
 
+// MyToken has no decimals(), amounts are whole units.
+const (
+	MYTOKEN_SYMBOL   = "Mytoken"
+	MYTOKEN_DECIMALS = 0
+)
+
+// ErrNoDecimals is returned by Decimals when the contract doesn't answer
+// decimals().
+var ErrNoDecimals = errors.New("Token has no decimals")
+
 type TokenWallet struct {
-	url    string
 	wallet *wallet.Wallet
+	token  common.Address
 }
 
-func NewTokenWallet(url, path, pass string, address string) (*TokenWallet, error) {
+// NewTokenWallet binds the wallet at address to the ERC-20 token contract,
+// an empty token uses the mytoken_address from the configuration.
+func NewTokenWallet(url, path, pass string, address string, token string) (*TokenWallet, error) {
 	w, err := wallet.LoadWallet(path, pass, address)
-	return &TokenWallet{wallet: w, url: url}, err
+	if err != nil {
+		return nil, err
+	}
+	if err := w.InitEthClient(url); err != nil {
+		return nil, err
+	}
+	if token == "" {
+		token = config.Config.MytokenAddress
+	}
+	return &TokenWallet{wallet: w, token: common.HexToAddress(token)}, nil
+}
+
+// NewTokenClient connects to the token without a wallet, only read calls
+// such as Metadata and Balacne can be used.
+func NewTokenClient(url string, token string) (*TokenWallet, error) {
+	w := &wallet.Wallet{}
+	if err := w.InitEthClient(url); err != nil {
+		return nil, err
+	}
+	return &TokenWallet{wallet: w, token: common.HexToAddress(token)}, nil
+}
+
+func (tw *TokenWallet) Client() *ethclient.Client {
+	return tw.wallet.Client
+}
+
+func (tw *TokenWallet) Close() {
+	tw.wallet.CloseClient()
+}
+
+func (tw *TokenWallet) Address() common.Address {
+	return tw.token
 }
 
 func (tw *TokenWallet) auth() (*bind.TransactOpts, error) {
 	if err := tw.wallet.KeyStore.Unlock(tw.wallet.Account, tw.wallet.Pass); err != nil {
-		return nil, err
+		return nil, wallet.Classify(err)
 	}
-	if err := tw.wallet.InitEthClient(tw.url); err != nil {
+
+	chainid, err := wallet.ChainID(tw.wallet.Client)
+	if err != nil {
 		return nil, err
 	}
-	defer tw.wallet.CloseClient()
 
-	chainid, err := tw.wallet.Client.NetworkID(context.Background())
+	auth, err := bind.NewKeyStoreTransactorWithChainID(tw.wallet.KeyStore, tw.wallet.Account, chainid)
 	if err != nil {
 		return nil, err
 	}
+	if auth.Nonce, err = tw.wallet.ReserveNonce(); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+// UseNonces takes nonces for the transactions of the token wallet from m.
+func (tw *TokenWallet) UseNonces(m *wallet.NonceManager) {
+	tw.wallet.Nonces = m
+}
 
-	return bind.NewKeyStoreTransactorWithChainID(tw.wallet.KeyStore, tw.wallet.Account, chainid)
+// sent gives back the nonce of auth when its transaction failed to send.
+func (tw *TokenWallet) sent(auth *bind.TransactOpts, tx *types.Transaction, err error) (*types.Transaction, error) {
+	if err != nil && auth.Nonce != nil {
+		tw.wallet.ReleaseNonce(auth.Nonce.Uint64())
+	}
+	return tx, wallet.Classify(err)
 }
 
 func (tw *TokenWallet) getSol() (*sol.Sol, error) {
-	address := common.HexToAddress(config.Config.MytokenAddress)
-	return sol.NewSol(address, tw.wallet.Client)
+	return sol.NewSol(tw.Address(), tw.wallet.Client)
 }
 
-func (tw *TokenWallet) Deploy() (string, string, error) {
-	auth, err := tw.auth()
+func (tw *TokenWallet) getERC20() (*sol.IERC20, error) {
+	return sol.NewIERC20(tw.Address(), tw.wallet.Client)
+}
+
+func (tw *TokenWallet) getMetadata() (*sol.IERC20Metadata, error) {
+	code, err := tw.wallet.Client.CodeAt(context.Background(), tw.Address(), nil)
+	if err != nil {
+		return nil, wallet.Classify(err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("No contract at %s", tw.Address().Hex())
+	}
+	return sol.NewIERC20Metadata(tw.Address(), tw.wallet.Client)
+}
+
+// Metadata reads the ERC-20 name, symbol and decimals, tokens without
+// name or symbol report empty strings. Decimals can't be guessed, failing to
+// read them is an error.
+func (tw *TokenWallet) Metadata() (name string, symbol string, decimals uint8, err error) {
+	instance, err := tw.getMetadata()
+	if err != nil {
+		return "", "", 0, err
+	}
+	opts := &bind.CallOpts{}
+	name, _ = instance.Name(opts)
+	symbol, _ = instance.Symbol(opts)
+	decimals, err = tw.Decimals()
+	return name, symbol, decimals, err
+}
+
+func (tw *TokenWallet) Decimals() (uint8, error) {
+	instance, err := tw.getMetadata()
 	if err != nil {
-		return "", "", err
+		return 0, err
 	}
+	decimals, err := instance.Decimals(&bind.CallOpts{})
+	if err = wallet.Classify(err); errors.Is(err, wallet.ErrRPCUnreachable) {
+		return 0, fmt.Errorf("Read decimals of %s error: %w", tw.Address().Hex(), err)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Read decimals of %s error: %w: %v", tw.Address().Hex(), ErrNoDecimals, err)
+	}
+	return decimals, nil
+}
 
+func (tw *TokenWallet) Deploy() (common.Address, *types.Transaction, error) {
 	parsed, err := sol.SolMetaData.GetAbi()
 	if err != nil {
-		return "", "", err
+		return common.Address{}, nil, err
 	}
 	if parsed == nil {
-		return "", "", errors.New("ABI Error")
+		return common.Address{}, nil, errors.New("ABI Error")
 	}
 
-	address, tx, _, err := sol.DeploySol(auth, tw.wallet.Client, "Mytoken")
-	return address.Hex(), tx.Hash().Hex(), nil
-}
-
-func (tw *TokenWallet) Mint(toaddr string, value int64) (string, error) {
 	auth, err := tw.auth()
 	if err != nil {
-		return "", err
+		return common.Address{}, nil, err
 	}
 
+	address, tx, _, err := sol.DeploySol(auth, tw.wallet.Client, MYTOKEN_SYMBOL)
+	tx, err = tw.sent(auth, tx, err)
+	return address, tx, err
+}
+
+func (tw *TokenWallet) Mint(toaddr string, value *big.Int) (*types.Transaction, error) {
 	instance, err := tw.getSol()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	tx, err := instance.Mint(auth, common.HexToAddress(toaddr), big.NewInt(value))
+
+	auth, err := tw.auth()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return tx.Hash().Hex(), nil
+
+	tx, err := instance.Mint(auth, common.HexToAddress(toaddr), value)
+	return tw.sent(auth, tx, err)
 }
 
-func (tw *TokenWallet) Transfer(toaddr string, value int64) (string, error) {
+func (tw *TokenWallet) Transfer(toaddr string, value *big.Int) (*types.Transaction, error) {
+	instance, err := tw.getERC20()
+	if err != nil {
+		return nil, err
+	}
+
 	auth, err := tw.auth()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	instance, err := tw.getSol()
+	tx, err := instance.Transfer(auth, common.HexToAddress(toaddr), value)
+	return tw.sent(auth, tx, err)
+}
+
+// TransferData packs the call data of transfer, to prepare a token transfer
+// that is signed elsewhere.
+func TransferData(toaddr string, value *big.Int) ([]byte, error) {
+	parsed, err := sol.IERC20MetaData.GetAbi()
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return parsed.Pack("transfer", common.HexToAddress(toaddr), value)
+}
+
+// ParseTransferData unpacks call data packed by TransferData, ok is false
+// for any other call.
+func ParseTransferData(data []byte) (to common.Address, value *big.Int, ok bool) {
+	parsed, err := sol.IERC20MetaData.GetAbi()
+	if err != nil || len(data) < 4 {
+		return to, nil, false
+	}
+	method, err := parsed.MethodById(data[:4])
+	if err != nil || method.Name != "transfer" {
+		return to, nil, false
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil || len(args) != 2 {
+		return to, nil, false
+	}
+	to, ok = args[0].(common.Address)
+	if !ok {
+		return to, nil, false
 	}
+	value, ok = args[1].(*big.Int)
+	return to, value, ok
+}
 
-	tx, err := instance.Transfer(auth, common.HexToAddress(toaddr), big.NewInt(value))
+func (tw *TokenWallet) Balacne(owner string) (*big.Int, error) {
+	instance, err := tw.getERC20()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return tx.Hash().Hex(), nil
+	value, err := instance.BalanceOf(&bind.CallOpts{}, common.HexToAddress(owner))
+	return value, wallet.Classify(err)
 }
 
-func (tw *TokenWallet) Balacne(owner string) (int64, error) {
-	if err := tw.wallet.InitEthClient(tw.url); err != nil {
-		return 0, err
+func (tw *TokenWallet) Approve(spender string, value *big.Int) (*types.Transaction, error) {
+	instance, err := tw.getERC20()
+	if err != nil {
+		return nil, err
 	}
-	defer tw.wallet.CloseClient()
 
-	instance, err := tw.getSol()
+	auth, err := tw.auth()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	val, err := instance.BalanceOf(&bind.CallOpts{}, common.HexToAddress(owner))
+	tx, err := instance.Approve(auth, common.HexToAddress(spender), value)
+	return tw.sent(auth, tx, err)
+}
+
+// SafeApprove first resets a non-zero allowance to zero and waits for it to
+// be mined before approving value, so a spender can't use both allowances.
+// The receipt of the reset is returned with it, nil when none was needed.
+func (tw *TokenWallet) SafeApprove(spender string, value *big.Int, wait wallet.WaitOpts) ([]*types.Transaction, *wallet.Receipt, error) {
+	current, err := tw.Allowance(tw.wallet.Account.Address.Hex(), spender)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
+	}
+
+	txs := []*types.Transaction{}
+	var reset *wallet.Receipt
+	if current.Sign() != 0 && value.Sign() != 0 {
+		tx, err := tw.Approve(spender, big.NewInt(0))
+		if err != nil {
+			return nil, nil, err
+		}
+		txs = append(txs, tx)
+
+		reset, err = wallet.WaitMined(tw.wallet.Client, tx, wait)
+		if err != nil {
+			return txs, nil, err
+		}
+		if !reset.Success() {
+			return txs, reset, fmt.Errorf("%w: reset allowance %s", wallet.ErrReverted, tx.Hash().Hex())
+		}
+	}
+
+	tx, err := tw.Approve(spender, value)
+	if err != nil {
+		return txs, reset, err
+	}
+	return append(txs, tx), reset, nil
+}
+
+func (tw *TokenWallet) Allowance(owner string, spender string) (*big.Int, error) {
+	instance, err := tw.getERC20()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := instance.Allowance(&bind.CallOpts{}, common.HexToAddress(owner), common.HexToAddress(spender))
+	return value, wallet.Classify(err)
+}
+
+func (tw *TokenWallet) TransferFrom(fromaddr string, toaddr string, value *big.Int) (*types.Transaction, error) {
+	instance, err := tw.getERC20()
+	if err != nil {
+		return nil, err
 	}
-	return val.Int64(), nil
+
+	auth, err := tw.auth()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := instance.TransferFrom(auth, common.HexToAddress(fromaddr), common.HexToAddress(toaddr), value)
+	return tw.sent(auth, tx, err)
 }
@@ -0,0 +1,110 @@
+package units
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	Wei   = 0
+	Gwei  = 9
+	Ether = 18
+)
+
+var unitDecimals = map[string]int{
+	"wei":    Wei,
+	"kwei":   3,
+	"mwei":   6,
+	"gwei":   Gwei,
+	"szabo":  12,
+	"finney": 15,
+	"ether":  Ether,
+	"eth":    Ether,
+}
+
+// ParseDecimal converts a decimal string such as "1.25" into base units with
+// the given number of decimals, rejecting values that need more precision.
+func ParseDecimal(value string, decimals int) (*big.Int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("empty amount")
+	}
+	intPart, fracPart := value, ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		intPart, fracPart = value[:i], value[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return nil, fmt.Errorf("invalid amount %q", value)
+	}
+	fracPart = strings.TrimRight(fracPart, "0")
+	if len(fracPart) > decimals {
+		return nil, fmt.Errorf("amount %q has more than %d decimals", value, decimals)
+	}
+	digits := intPart + fracPart + strings.Repeat("0", decimals-len(fracPart))
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("invalid amount %q", value)
+		}
+	}
+	ret, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", value)
+	}
+	return ret, nil
+}
+
+// ParseUnit converts an amount with an optional unit suffix such as "1.5ether",
+// "20 gwei" or "100wei" into wei, amounts without a suffix use defaultUnit.
+func ParseUnit(value string, defaultUnit string) (*big.Int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	unit := ""
+	// "gwei" also ends in "wei", keep the longest matching suffix
+	for name := range unitDecimals {
+		if strings.HasSuffix(value, name) && len(name) > len(unit) {
+			unit = name
+		}
+	}
+	if unit == "" {
+		unit = defaultUnit
+	} else {
+		value = strings.TrimSpace(strings.TrimSuffix(value, unit))
+	}
+	decimals, ok := unitDecimals[unit]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", unit)
+	}
+	return ParseDecimal(value, decimals)
+}
+
+// Format renders base units as an exact decimal string without trailing zeros.
+func Format(value *big.Int, decimals int) string {
+	if value == nil {
+		return "0"
+	}
+	digits := new(big.Int).Abs(value).String()
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+	}
+	if decimals <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	intPart := digits[:len(digits)-decimals]
+	fracPart := strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fracPart == "" {
+		return sign + intPart
+	}
+	return sign + intPart + "." + fracPart
+}
+
+func FormatEther(wei *big.Int) string {
+	return Format(wei, Ether)
+}
+
+func FormatGwei(wei *big.Int) string {
+	return Format(wei, Gwei)
+}
@@ -0,0 +1,102 @@
+package units
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		value    string
+		decimals int
+		want     string
+		fail     bool
+	}{
+		{value: "1", decimals: 18, want: "1000000000000000000"},
+		{value: "1.25", decimals: 18, want: "1250000000000000000"},
+		{value: " 0.5 ", decimals: 6, want: "500000"},
+		{value: ".5", decimals: 1, want: "5"},
+		{value: "5.", decimals: 2, want: "500"},
+		{value: "1.500", decimals: 1, want: "15"},
+		{value: "0.000000000000000001", decimals: 18, want: "1"},
+		{value: "42", decimals: 0, want: "42"},
+		{value: "1.5", decimals: 0, fail: true},
+		{value: "0.0000001", decimals: 6, fail: true},
+		{value: "", decimals: 18, fail: true},
+		{value: ".", decimals: 18, fail: true},
+		{value: "-1", decimals: 18, fail: true},
+		{value: "1e18", decimals: 18, fail: true},
+		{value: "1.2.3", decimals: 18, fail: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDecimal(tt.value, tt.decimals)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("ParseDecimal(%q, %d) = %s, want error", tt.value, tt.decimals, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDecimal(%q, %d) error: %v", tt.value, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseDecimal(%q, %d) = %s, want %s", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestParseUnit(t *testing.T) {
+	tests := []struct {
+		value string
+		unit  string
+		want  string
+		fail  bool
+	}{
+		{value: "1.5ether", unit: "wei", want: "1500000000000000000"},
+		{value: "1 ETH", unit: "wei", want: "1000000000000000000"},
+		{value: "20 gwei", unit: "ether", want: "20000000000"},
+		{value: "100wei", unit: "ether", want: "100"},
+		{value: "2", unit: "gwei", want: "2000000000"},
+		{value: "0.1", unit: "ether", want: "100000000000000000"},
+		{value: "0.5wei", unit: "ether", fail: true},
+		{value: "1", unit: "bogus", fail: true},
+		{value: "gwei", unit: "ether", fail: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseUnit(tt.value, tt.unit)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("ParseUnit(%q, %q) = %s, want error", tt.value, tt.unit, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseUnit(%q, %q) error: %v", tt.value, tt.unit, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseUnit(%q, %q) = %s, want %s", tt.value, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		value    string
+		decimals int
+		want     string
+	}{
+		{value: "1500000000000000000", decimals: 18, want: "1.5"},
+		{value: "1", decimals: 18, want: "0.000000000000000001"},
+		{value: "-250", decimals: 2, want: "-2.5"},
+		{value: "0", decimals: 18, want: "0"},
+		{value: "42", decimals: 0, want: "42"},
+	}
+	for _, tt := range tests {
+		value, _ := new(big.Int).SetString(tt.value, 10)
+		if got := Format(value, tt.decimals); got != tt.want {
+			t.Errorf("Format(%s, %d) = %s, want %s", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package wallet
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ParseAddress validates a hex address, mixed case input must match its
+// EIP-55 checksum. checksummed is false for all lower or upper case input.
+func ParseAddress(s string) (address common.Address, checksummed bool, err error) {
+	if !common.IsHexAddress(s) {
+		return common.Address{}, false, fmt.Errorf("Invalid address %s", s)
+	}
+	address = common.HexToAddress(s)
+	hex := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if hex == strings.ToLower(hex) || hex == strings.ToUpper(hex) {
+		return address, false, nil
+	}
+	if address.Hex()[2:] != hex {
+		return common.Address{}, false, fmt.Errorf("Invalid address checksum %s", s)
+	}
+	return address, true, nil
+}
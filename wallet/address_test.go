@@ -0,0 +1,41 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	const checksummed = "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"
+	tests := []struct {
+		name        string
+		input       string
+		checksummed bool
+		fail        string
+	}{
+		{name: "checksummed", input: checksummed, checksummed: true},
+		{name: "lower case", input: strings.ToLower(checksummed)},
+		{name: "upper case", input: "0x" + strings.ToUpper(checksummed[2:])},
+		{name: "no prefix", input: checksummed[2:], checksummed: true},
+		{name: "wrong checksum", input: "0x9858efFD232B4033E47d90003D41EC34EcaEda94", fail: "Invalid address checksum"},
+		{name: "short", input: "0x9858EfFD232B4033E47d90003D41EC34EcaEda9", fail: "Invalid address"},
+		{name: "not hex", input: "0x9858EfFD232B4033E47d90003D41EC34EcaEda9z", fail: "Invalid address"},
+		{name: "name", input: "alice", fail: "Invalid address"},
+	}
+	for _, tt := range tests {
+		address, ok, err := ParseAddress(tt.input)
+		if tt.fail != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.fail) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.fail)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if address.Hex() != checksummed || ok != tt.checksummed {
+			t.Errorf("%s: %s checksummed %v, want %s %v", tt.name, address.Hex(), ok, checksummed, tt.checksummed)
+		}
+	}
+}
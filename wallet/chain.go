@@ -0,0 +1,29 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/qxoo/mywallet/config"
+)
+
+// ChainID returns the EIP-155 chain id to sign with, refusing a node that
+// is on another chain than the chain_id of the network profile.
+func ChainID(client *ethclient.Client) (*big.Int, error) {
+	chainid, err := client.ChainID(context.Background())
+	if err != nil {
+		return nil, Classify(err)
+	}
+
+	expected := config.Config.ChainID
+	if expected != 0 && (!chainid.IsUint64() || chainid.Uint64() != expected) {
+		network := config.Config.Name
+		if network == "" {
+			network = "config"
+		}
+		return nil, fmt.Errorf("%w: node reports chain id %s, %s expects %d", ErrChainMismatch, chainid, network, expected)
+	}
+	return chainid, nil
+}
@@ -0,0 +1,49 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+var (
+	ErrKeyNotFound       = errors.New("Key not found in keystore")
+	ErrWrongPassword     = errors.New("Wrong password")
+	ErrInsufficientFunds = errors.New("Insufficient funds")
+	ErrRPCUnreachable    = errors.New("RPC unreachable")
+	ErrReverted          = errors.New("Transaction reverted")
+	ErrChainMismatch     = errors.New("Chain id mismatch")
+)
+
+// Classify wraps keystore, connection and node errors with the matching
+// typed error, errors it doesn't recognize are returned as is.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, typed := range []error{ErrKeyNotFound, ErrWrongPassword, ErrInsufficientFunds, ErrRPCUnreachable, ErrReverted, ErrChainMismatch} {
+		if errors.Is(err, typed) {
+			return err
+		}
+	}
+
+	var neterr net.Error
+	var urlerr *url.Error
+	switch {
+	case errors.Is(err, keystore.ErrDecrypt):
+		return fmt.Errorf("%w: %v", ErrWrongPassword, err)
+	case errors.Is(err, keystore.ErrNoMatch):
+		return fmt.Errorf("%w: %v", ErrKeyNotFound, err)
+	case errors.Is(err, syscall.ECONNREFUSED), errors.As(err, &neterr), errors.As(err, &urlerr):
+		return fmt.Errorf("%w: %v", ErrRPCUnreachable, err)
+	// node errors only arrive as json-rpc messages
+	case strings.Contains(err.Error(), "insufficient funds"):
+		return fmt.Errorf("%w: %v", ErrInsufficientFunds, err)
+	}
+	return err
+}
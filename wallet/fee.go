@@ -0,0 +1,132 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/qxoo/mywallet/config"
+	"github.com/qxoo/mywallet/units"
+)
+
+// FeeOpts overrides the fees suggested by the node, nil fields are suggested.
+type FeeOpts struct {
+	MaxFee *big.Int
+	Tip    *big.Int
+}
+
+// Fees holds either a legacy GasPrice or the EIP-1559 fee caps.
+type Fees struct {
+	GasPrice  *big.Int
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+}
+
+func (f *Fees) Dynamic() bool {
+	return f.GasFeeCap != nil
+}
+
+// MaxPrice is the most that can be paid per unit of gas.
+func (f *Fees) MaxPrice() *big.Int {
+	if f.Dynamic() {
+		return f.GasFeeCap
+	}
+	return f.GasPrice
+}
+
+func (f *Fees) String() string {
+	if f.Dynamic() {
+		return fmt.Sprintf("max fee %s gwei, tip %s gwei", units.FormatGwei(f.GasFeeCap), units.FormatGwei(f.GasTipCap))
+	}
+	return fmt.Sprintf("gas price %s gwei", units.FormatGwei(f.GasPrice))
+}
+
+func (f *Fees) NewTx(chainid *big.Int, nonce uint64, to *common.Address, amount *big.Int, gas uint64, data []byte) *types.Transaction {
+	if f.Dynamic() {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainid,
+			Nonce:     nonce,
+			GasTipCap: f.GasTipCap,
+			GasFeeCap: f.GasFeeCap,
+			Gas:       gas,
+			To:        to,
+			Value:     amount,
+			Data:      data,
+		})
+	}
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: f.GasPrice,
+		Gas:      gas,
+		To:       to,
+		Value:    amount,
+		Data:     data,
+	})
+}
+
+func feeCap(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return units.ParseUnit(value, "gwei")
+}
+
+func minBig(a, b *big.Int) *big.Int {
+	if b != nil && a.Cmp(b) > 0 {
+		return new(big.Int).Set(b)
+	}
+	return a
+}
+
+// SuggestFees builds dynamic fees from the latest base fee, falling back to a
+// legacy gas price when the chain has no base fee. Suggested values are
+// limited by the max_fee and max_tip caps in the configuration.
+func SuggestFees(client *ethclient.Client, opts FeeOpts) (*Fees, error) {
+	maxFeeCap, err := feeCap(config.Config.MaxFee)
+	if err != nil {
+		return nil, fmt.Errorf("Config max_fee error: %v", err)
+	}
+	maxTipCap, err := feeCap(config.Config.MaxTip)
+	if err != nil {
+		return nil, fmt.Errorf("Config max_tip error: %v", err)
+	}
+
+	head, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if head.BaseFee == nil {
+		gasprice := opts.MaxFee
+		if gasprice == nil {
+			if gasprice, err = client.SuggestGasPrice(context.Background()); err != nil {
+				return nil, err
+			}
+			gasprice = minBig(gasprice, maxFeeCap)
+		}
+		return &Fees{GasPrice: gasprice}, nil
+	}
+
+	tip := opts.Tip
+	if tip == nil {
+		if tip, err = client.SuggestGasTipCap(context.Background()); err != nil {
+			return nil, err
+		}
+		tip = minBig(tip, maxTipCap)
+	}
+
+	maxfee := opts.MaxFee
+	if maxfee == nil {
+		maxfee = new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tip)
+		maxfee = minBig(maxfee, maxFeeCap)
+	}
+	if maxfee.Cmp(head.BaseFee) < 0 {
+		return nil, fmt.Errorf("Max fee %s gwei is below base fee %s gwei", units.FormatGwei(maxfee), units.FormatGwei(head.BaseFee))
+	}
+	tip = minBig(tip, maxfee)
+
+	return &Fees{GasFeeCap: maxfee, GasTipCap: tip}, nil
+}
@@ -0,0 +1,38 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/qxoo/mywallet/config"
+)
+
+// EstimateGas estimates msg and applies the configured gas_multiplier, the
+// gas_limit from the configuration is only used as a ceiling.
+func EstimateGas(client *ethclient.Client, msg ethereum.CallMsg) (uint64, error) {
+	gas, err := client.EstimateGas(context.Background(), msg)
+	if err != nil {
+		return 0, err
+	}
+
+	ceiling := config.Config.GasLimit
+	if ceiling > 0 && gas > ceiling {
+		return 0, fmt.Errorf("Estimated gas %d exceeds gas_limit %d", gas, ceiling)
+	}
+
+	// a plain value transfer always costs exactly the intrinsic gas
+	if gas == params.TxGas && len(msg.Data) == 0 {
+		return gas, nil
+	}
+
+	if multiplier := config.Config.GasMultiplier; multiplier > 1 {
+		gas = uint64(float64(gas) * multiplier)
+	}
+	if ceiling > 0 && gas > ceiling {
+		gas = ceiling
+	}
+	return gas, nil
+}
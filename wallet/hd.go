@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// HDPath returns the BIP44 ethereum path m/44'/60'/account'/change/index.
+// Each level must be below 2^31, higher values would be hardened keys.
+func HDPath(account, change, index uint64) (accounts.DerivationPath, error) {
+	for _, level := range []struct {
+		name  string
+		value uint64
+	}{{"account", account}, {"change", change}, {"index", index}} {
+		if level.value >= hdkeychain.HardenedKeyStart {
+			return nil, fmt.Errorf("%s %d is out of range, it must be below %d", level.name, level.value, uint32(hdkeychain.HardenedKeyStart))
+		}
+	}
+	return accounts.DerivationPath{
+		hdkeychain.HardenedKeyStart + 44,
+		hdkeychain.HardenedKeyStart + 60,
+		hdkeychain.HardenedKeyStart + uint32(account),
+		uint32(change),
+		uint32(index),
+	}, nil
+}
+
+// PrivateFromPath derives the key at path from the mnemonic, the passphrase
+// is the optional BIP39 "25th word" and is not the keystore password.
+func PrivateFromPath(words string, passphrase string, path accounts.DerivationPath) (*ecdsa.PrivateKey, *common.Address, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(words, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	masterKey, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, err := DerivePrivateKey(path, masterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	_publicKey := privateKey.Public()
+	publicKey, ok := _publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("public error")
+	}
+	address := crypto.PubkeyToAddress(*publicKey)
+	return privateKey, &address, nil
+}
+
+// SeedID identifies a mnemonic by the address of its default path, so
+// wallets created before HD support map to the same seed.
+func SeedID(words string, passphrase string) (string, error) {
+	_, address, err := PrivateFromWords(words, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return address.Hex(), nil
+}
+
+// NextIndex returns the first unused address index under the account and
+// change levels of base, given the paths already derived from the seed.
+func NextIndex(base accounts.DerivationPath, derived []string) uint32 {
+	var next uint32
+	for _, p := range derived {
+		path, err := accounts.ParseDerivationPath(p)
+		if err != nil || len(path) != len(base) {
+			continue
+		}
+		if path[2] != base[2] || path[3] != base[3] {
+			continue
+		}
+		if path[4] >= next {
+			next = path[4] + 1
+		}
+	}
+	return next
+}
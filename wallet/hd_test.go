@@ -0,0 +1,34 @@
+package wallet
+
+import "testing"
+
+func TestHDPath(t *testing.T) {
+	tests := []struct {
+		account, change, index uint64
+		want                   string
+	}{
+		{0, 0, 0, "m/44'/60'/0'/0/0"},
+		{1, 1, 5, "m/44'/60'/1'/1/5"},
+		{1<<31 - 1, 0, 1<<31 - 1, "m/44'/60'/2147483647'/0/2147483647"},
+		{account: 1 << 31},
+		{change: 1 << 31},
+		{index: 1 << 31},
+		{account: 1<<32 + 1},
+	}
+	for _, tt := range tests {
+		path, err := HDPath(tt.account, tt.change, tt.index)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("HDPath(%d, %d, %d) = %s, want error", tt.account, tt.change, tt.index, path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("HDPath(%d, %d, %d) error: %v", tt.account, tt.change, tt.index, err)
+			continue
+		}
+		if path.String() != tt.want {
+			t.Errorf("HDPath(%d, %d, %d) = %s, want %s", tt.account, tt.change, tt.index, path, tt.want)
+		}
+	}
+}
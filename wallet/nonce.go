@@ -0,0 +1,70 @@
+package wallet
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// NONCE_TIMEOUT is how long a reserved nonce the node hasn't seen is taken
+// as still being sent, after it the nonce is taken as dropped and reused.
+const NONCE_TIMEOUT = 2 * time.Minute
+
+// NonceStore keeps the next nonce of each account between runs, db.DB
+// implements it. UpdateNonce must call fn and store its result atomically.
+type NonceStore interface {
+	UpdateNonce(address string, fn func(next uint64, updated time.Time) (uint64, error)) error
+}
+
+// NonceManager reserves nonces so transactions sent quickly one after
+// another, or by several processes, don't get the pending nonce of the node
+// twice. OnGap is told about reserved nonces that never reached the node.
+type NonceManager struct {
+	Store   NonceStore
+	Timeout time.Duration
+	OnGap   func(address common.Address, from, to uint64)
+}
+
+// Reserve returns the nonce for the next transaction of address, the
+// pending nonce of the node unless nonces above it were reserved recently.
+func (m *NonceManager) Reserve(client *ethclient.Client, address common.Address) (uint64, error) {
+	pending, err := client.PendingNonceAt(context.Background(), address)
+	if err != nil {
+		return 0, Classify(err)
+	}
+	return m.reserve(address, pending)
+}
+
+func (m *NonceManager) reserve(address common.Address, pending uint64) (uint64, error) {
+	timeout := m.Timeout
+	if timeout == 0 {
+		timeout = NONCE_TIMEOUT
+	}
+
+	var nonce uint64
+	err := m.Store.UpdateNonce(address.Hex(), func(next uint64, updated time.Time) (uint64, error) {
+		nonce = pending
+		if next > pending {
+			if time.Since(updated) < timeout {
+				nonce = next
+			} else if m.OnGap != nil {
+				m.OnGap(address, pending, next-1)
+			}
+		}
+		return nonce + 1, nil
+	})
+	return nonce, err
+}
+
+// Release gives back nonce after its transaction failed to send, only the
+// last reserved nonce can be given back.
+func (m *NonceManager) Release(address common.Address, nonce uint64) error {
+	return m.Store.UpdateNonce(address.Hex(), func(next uint64, updated time.Time) (uint64, error) {
+		if next == nonce+1 {
+			return nonce, nil
+		}
+		return next, nil
+	})
+}
@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type storedNonce struct {
+	next    uint64
+	updated time.Time
+}
+
+// memNonces is a NonceStore in memory.
+type memNonces map[string]storedNonce
+
+func (s memNonces) UpdateNonce(address string, fn func(next uint64, updated time.Time) (uint64, error)) error {
+	stored := s[address]
+	next, err := fn(stored.next, stored.updated)
+	if err != nil {
+		return err
+	}
+	s[address] = storedNonce{next: next, updated: time.Now()}
+	return nil
+}
+
+func TestNonceReserve(t *testing.T) {
+	address := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	recent, stale := time.Now().Add(-time.Second), time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		stored  *storedNonce
+		pending uint64
+		want    uint64
+		gap     []uint64
+	}{
+		{name: "first use", pending: 5, want: 5},
+		{name: "node ahead", stored: &storedNonce{next: 3, updated: recent}, pending: 5, want: 5},
+		{name: "node caught up", stored: &storedNonce{next: 5, updated: recent}, pending: 5, want: 5},
+		{name: "reserved recently", stored: &storedNonce{next: 7, updated: recent}, pending: 5, want: 7},
+		{name: "reserved long ago", stored: &storedNonce{next: 7, updated: stale}, pending: 5, want: 5, gap: []uint64{5, 6}},
+	}
+	for _, tt := range tests {
+		store := memNonces{}
+		if tt.stored != nil {
+			store[address.Hex()] = *tt.stored
+		}
+		var gap []uint64
+		m := &NonceManager{Store: store, Timeout: time.Minute, OnGap: func(_ common.Address, from, to uint64) {
+			gap = []uint64{from, to}
+		}}
+
+		got, err := m.reserve(address, tt.pending)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: reserved %d, want %d", tt.name, got, tt.want)
+		}
+		if next := store[address.Hex()].next; next != tt.want+1 {
+			t.Errorf("%s: stored next %d, want %d", tt.name, next, tt.want+1)
+		}
+		if len(gap) != len(tt.gap) || (len(gap) == 2 && (gap[0] != tt.gap[0] || gap[1] != tt.gap[1])) {
+			t.Errorf("%s: gap %v, want %v", tt.name, gap, tt.gap)
+		}
+	}
+}
+
+func TestNonceReserveInSequence(t *testing.T) {
+	address := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	m := &NonceManager{Store: memNonces{}}
+	// the node doesn't see the first transactions yet
+	for want := uint64(0); want < 3; want++ {
+		got, err := m.reserve(address, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("reserved %d, want %d", got, want)
+		}
+	}
+}
+
+func TestNonceRelease(t *testing.T) {
+	address := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	tests := []struct {
+		name    string
+		next    uint64
+		release uint64
+		want    uint64
+	}{
+		{name: "last reserved", next: 8, release: 7, want: 7},
+		{name: "older nonce", next: 8, release: 6, want: 8},
+		{name: "not reserved", next: 8, release: 8, want: 8},
+	}
+	for _, tt := range tests {
+		store := memNonces{address.Hex(): {next: tt.next, updated: time.Now()}}
+		m := &NonceManager{Store: store}
+		if err := m.Release(address, tt.release); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if next := store[address.Hex()].next; next != tt.want {
+			t.Errorf("%s: next %d, want %d", tt.name, next, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TxParams holds what Transfer asks the node for, so a transaction can be
+// signed without one.
+type TxParams struct {
+	Nonce   uint64
+	Gas     uint64
+	ChainID *big.Int
+	Fees    Fees
+}
+
+// SignedTx is the json envelope of a signed transaction, Raw is enough to
+// broadcast it, the other fields are for review before broadcasting.
+type SignedTx struct {
+	Raw     string `json:"raw"`
+	Hash    string `json:"hash"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Value   string `json:"value"`
+	Nonce   uint64 `json:"nonce"`
+	Gas     uint64 `json:"gas"`
+	ChainID string `json:"chain_id"`
+}
+
+// UnsignedTx is a transaction prepared on a machine with a node for a
+// machine with the keystore, amounts are in wei. Token, Symbol, Recipient
+// and Amount describe a token transfer for review, only the other fields
+// are signed.
+type UnsignedTx struct {
+	Kind      string `json:"kind"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Value     string `json:"value"`
+	Data      string `json:"data,omitempty"`
+	Nonce     uint64 `json:"nonce"`
+	Gas       uint64 `json:"gas"`
+	ChainID   string `json:"chain_id"`
+	GasPrice  string `json:"gas_price,omitempty"`
+	MaxFee    string `json:"max_fee,omitempty"`
+	Tip       string `json:"tip,omitempty"`
+	Token     string `json:"token,omitempty"`
+	Symbol    string `json:"symbol,omitempty"`
+	Recipient string `json:"recipient,omitempty"`
+	Amount    string `json:"amount,omitempty"`
+}
+
+func NewUnsignedTx(kind string, from common.Address, toaddr string, amount *big.Int, data []byte, params TxParams) *UnsignedTx {
+	unsigned := &UnsignedTx{
+		Kind:    kind,
+		From:    from.Hex(),
+		To:      common.HexToAddress(toaddr).Hex(),
+		Value:   amount.String(),
+		Nonce:   params.Nonce,
+		Gas:     params.Gas,
+		ChainID: params.ChainID.String(),
+	}
+	if len(data) > 0 {
+		unsigned.Data = hexutil.Encode(data)
+	}
+	if params.Fees.Dynamic() {
+		unsigned.MaxFee = params.Fees.GasFeeCap.String()
+		unsigned.Tip = params.Fees.GasTipCap.String()
+	} else {
+		unsigned.GasPrice = params.Fees.GasPrice.String()
+	}
+	return unsigned
+}
+
+func parseWei(field, value string) (*big.Int, error) {
+	wei, ok := new(big.Int).SetString(value, 10)
+	if !ok || wei.Sign() < 0 {
+		return nil, fmt.Errorf("Invalid %s: %q", field, value)
+	}
+	return wei, nil
+}
+
+// Params reads back the transaction of a prepared UnsignedTx.
+func (u *UnsignedTx) Params() (amount *big.Int, data []byte, params TxParams, err error) {
+	if !common.IsHexAddress(u.From) || !common.IsHexAddress(u.To) {
+		return nil, nil, params, fmt.Errorf("Invalid from or to address")
+	}
+	if amount, err = parseWei("value", u.Value); err != nil {
+		return nil, nil, params, err
+	}
+	if u.Data != "" {
+		if data, err = hexutil.Decode(u.Data); err != nil {
+			return nil, nil, params, fmt.Errorf("Invalid data: %v", err)
+		}
+	}
+	if params.ChainID, err = parseWei("chain_id", u.ChainID); err != nil {
+		return nil, nil, params, err
+	}
+	params.Nonce = u.Nonce
+	params.Gas = u.Gas
+
+	switch {
+	case u.MaxFee != "":
+		if params.Fees.GasFeeCap, err = parseWei("max_fee", u.MaxFee); err != nil {
+			return nil, nil, params, err
+		}
+		if params.Fees.GasTipCap, err = parseWei("tip", u.Tip); err != nil {
+			return nil, nil, params, err
+		}
+	case u.GasPrice != "":
+		if params.Fees.GasPrice, err = parseWei("gas_price", u.GasPrice); err != nil {
+			return nil, nil, params, err
+		}
+	default:
+		return nil, nil, params, fmt.Errorf("Missing gas_price or max_fee")
+	}
+	return amount, data, params, nil
+}
+
+// Sign signs a transaction from params only, it needs no eth client.
+func (w *Wallet) Sign(toaddr string, amount *big.Int, data []byte, params TxParams) (*types.Transaction, error) {
+	if params.ChainID == nil || params.ChainID.Sign() <= 0 {
+		return nil, fmt.Errorf("Sign needs a chain id")
+	}
+	if params.Fees.MaxPrice() == nil {
+		return nil, fmt.Errorf("Sign needs a gas price or max fee")
+	}
+
+	to_addr := common.HexToAddress(toaddr)
+	tx := params.Fees.NewTx(params.ChainID, params.Nonce, &to_addr, amount, params.Gas, data)
+	return w.SignTx(tx, params.ChainID)
+}
+
+func (w *Wallet) SignTx(tx *types.Transaction, chainid *big.Int) (*types.Transaction, error) {
+	if err := w.KeyStore.Unlock(w.Account, w.Pass); err != nil {
+		return nil, Classify(err)
+	}
+	signedTx, err := w.KeyStore.SignTx(w.Account, tx, chainid)
+	if err != nil {
+		return nil, Classify(err)
+	}
+	return signedTx, nil
+}
+
+// TxSender recovers the sender from the signature of tx.
+func TxSender(tx *types.Transaction) (common.Address, error) {
+	return types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+}
+
+func NewSignedTx(tx *types.Transaction) (*SignedTx, error) {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	from, err := TxSender(tx)
+	if err != nil {
+		return nil, err
+	}
+	to := ""
+	if tx.To() != nil {
+		to = tx.To().Hex()
+	}
+	return &SignedTx{
+		Raw:     hexutil.Encode(raw),
+		Hash:    tx.Hash().Hex(),
+		From:    from.Hex(),
+		To:      to,
+		Value:   tx.Value().String(),
+		Nonce:   tx.Nonce(),
+		Gas:     tx.Gas(),
+		ChainID: tx.ChainId().String(),
+	}, nil
+}
+
+// DecodeTx reads a signed transaction written by sign, either the raw hex
+// or the json envelope.
+func DecodeTx(content []byte) (*types.Transaction, error) {
+	text := strings.TrimSpace(string(content))
+	if strings.HasPrefix(text, "{") {
+		envelope := SignedTx{}
+		if err := json.Unmarshal([]byte(text), &envelope); err != nil {
+			return nil, err
+		}
+		text = envelope.Raw
+	}
+	raw, err := hexutil.Decode(text)
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
@@ -0,0 +1,146 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestUnsignedTxParams(t *testing.T) {
+	from := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	to := "0x000000000000000000000000000000000000dEaD"
+	data := []byte{0xa9, 0x05, 0x9c, 0xbb}
+	prepared := func(fees Fees) UnsignedTx {
+		params := TxParams{Nonce: 7, Gas: 60000, ChainID: big.NewInt(1337), Fees: fees}
+		return *NewUnsignedTx("transfer", from, to, big.NewInt(1000), data, params)
+	}
+	dynamic := prepared(Fees{GasFeeCap: big.NewInt(30), GasTipCap: big.NewInt(2)})
+	legacy := prepared(Fees{GasPrice: big.NewInt(20)})
+
+	tests := []struct {
+		name   string
+		edit   func(u *UnsignedTx)
+		legacy bool
+		fail   string
+	}{
+		{name: "dynamic"},
+		{name: "legacy", legacy: true},
+		{name: "no data", edit: func(u *UnsignedTx) { u.Data = "" }},
+		{name: "bad from", edit: func(u *UnsignedTx) { u.From = "alice" }, fail: "Invalid from or to address"},
+		{name: "bad to", edit: func(u *UnsignedTx) { u.To = "0x1234" }, fail: "Invalid from or to address"},
+		{name: "bad value", edit: func(u *UnsignedTx) { u.Value = "1.5" }, fail: "Invalid value"},
+		{name: "negative value", edit: func(u *UnsignedTx) { u.Value = "-1" }, fail: "Invalid value"},
+		{name: "bad data", edit: func(u *UnsignedTx) { u.Data = "a9059cbb" }, fail: "Invalid data"},
+		{name: "bad chain", edit: func(u *UnsignedTx) { u.ChainID = "" }, fail: "Invalid chain_id"},
+		{name: "bad tip", edit: func(u *UnsignedTx) { u.Tip = "" }, fail: "Invalid tip"},
+		{name: "bad gas price", legacy: true, edit: func(u *UnsignedTx) { u.GasPrice = "20gwei" }, fail: "Invalid gas_price"},
+		{name: "no fees", edit: func(u *UnsignedTx) { u.MaxFee, u.Tip = "", "" }, fail: "Missing gas_price or max_fee"},
+	}
+	for _, tt := range tests {
+		u := dynamic
+		if tt.legacy {
+			u = legacy
+		}
+		if tt.edit != nil {
+			tt.edit(&u)
+		}
+
+		amount, gotData, params, err := u.Params()
+		if tt.fail != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.fail) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.fail)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if amount.Int64() != 1000 || params.Nonce != 7 || params.Gas != 60000 || params.ChainID.Int64() != 1337 {
+			t.Errorf("%s: amount %s nonce %d gas %d chain %s", tt.name, amount, params.Nonce, params.Gas, params.ChainID)
+		}
+		if u.Data != "" && !bytes.Equal(gotData, data) {
+			t.Errorf("%s: data %x, want %x", tt.name, gotData, data)
+		}
+		if u.Data == "" && gotData != nil {
+			t.Errorf("%s: data %x, want none", tt.name, gotData)
+		}
+		if tt.legacy {
+			if params.Fees.Dynamic() || params.Fees.GasPrice.Int64() != 20 {
+				t.Errorf("%s: fees %s, want gas price 20", tt.name, &params.Fees)
+			}
+		} else if !params.Fees.Dynamic() || params.Fees.GasFeeCap.Int64() != 30 || params.Fees.GasTipCap.Int64() != 2 {
+			t.Errorf("%s: fees %s, want max fee 30 tip 2", tt.name, &params.Fees)
+		}
+	}
+}
+
+func TestSignRoundTrip(t *testing.T) {
+	const words = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	from := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	hdpath, err := HDPath(0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if _, err := ImportWallet(words, dir, "pw", "", hdpath); err != nil {
+		t.Fatal(err)
+	}
+	w, err := LoadWallet(dir, "pw", from.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	to := "0x000000000000000000000000000000000000dEaD"
+	for _, fees := range []Fees{{GasPrice: big.NewInt(20)}, {GasFeeCap: big.NewInt(30), GasTipCap: big.NewInt(2)}} {
+		params := TxParams{Nonce: 7, Gas: 21000, ChainID: big.NewInt(1337), Fees: fees}
+		tx, err := w.Sign(to, big.NewInt(1000), nil, params)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", &fees, err)
+			continue
+		}
+		signed, err := NewSignedTx(tx)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", &fees, err)
+			continue
+		}
+		if signed.From != from.Hex() || signed.To != to || signed.ChainID != "1337" || signed.Nonce != 7 || signed.Value != "1000" {
+			t.Errorf("%s: signed %+v", &fees, signed)
+		}
+
+		envelope, err := json.Marshal(signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, content := range [][]byte{[]byte(signed.Raw + "\n"), envelope} {
+			decoded, err := DecodeTx(content)
+			if err != nil {
+				t.Errorf("%s: decode %.20s: %v", &fees, content, err)
+				continue
+			}
+			sender, err := TxSender(decoded)
+			if err != nil || sender != from {
+				t.Errorf("%s: sender %s, %v, want %s", &fees, sender.Hex(), err, from.Hex())
+			}
+			raw, _ := decoded.MarshalBinary()
+			if decoded.ChainId().Int64() != 1337 || hexutil.Encode(raw) != signed.Raw || decoded.Hash().Hex() != signed.Hash {
+				t.Errorf("%s: decoded chain %s hash %s, want 1337 %s", &fees, decoded.ChainId(), decoded.Hash().Hex(), signed.Hash)
+			}
+		}
+	}
+
+	w.Pass = "wrong"
+	params := TxParams{Nonce: 7, Gas: 21000, ChainID: big.NewInt(1337), Fees: Fees{GasPrice: big.NewInt(20)}}
+	if _, err := w.Sign(to, big.NewInt(1000), nil, params); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("wrong password: error %v, want ErrWrongPassword", err)
+	}
+	if _, err := DecodeTx([]byte("0x1234")); err == nil {
+		t.Errorf("decode garbage: no error")
+	}
+}
@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+type WaitOpts struct {
+	Confirmations uint64
+	Timeout       time.Duration
+}
+
+type Receipt struct {
+	*types.Receipt
+	GasPrice *big.Int
+	Fee      *big.Int
+}
+
+func (r *Receipt) Success() bool {
+	return r.Status == types.ReceiptStatusSuccessful
+}
+
+func (r *Receipt) StatusText() string {
+	if r.Success() {
+		return "success"
+	}
+	return "reverted"
+}
+
+// WaitMined polls for the receipt of tx until it has the requested number of
+// confirmations, re-reading the receipt afterwards in case of a reorg.
+func WaitMined(client *ethclient.Client, tx *types.Transaction, opts WaitOpts) (*Receipt, error) {
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var receipt *types.Receipt
+	for {
+		var err error
+		if receipt == nil {
+			if receipt, err = bind.WaitMined(ctx, client, tx); err != nil {
+				return nil, err
+			}
+		}
+
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Confirmations <= 1 || head+1 >= receipt.BlockNumber.Uint64()+opts.Confirmations {
+			receipt, err = client.TransactionReceipt(ctx, tx.Hash())
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, ethereum.NotFound) {
+				return nil, err
+			}
+			receipt = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return newReceipt(ctx, client, tx, receipt)
+}
+
+// FetchReceipt reads the receipt of the transaction hash without waiting,
+// ethereum.NotFound is returned while it is not mined.
+func FetchReceipt(client *ethclient.Client, hash common.Hash) (*Receipt, error) {
+	ctx := context.Background()
+	receipt, err := client.TransactionReceipt(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	tx, _, err := client.TransactionByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return newReceipt(ctx, client, tx, receipt)
+}
+
+func newReceipt(ctx context.Context, client *ethclient.Client, tx *types.Transaction, receipt *types.Receipt) (*Receipt, error) {
+	header, err := client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	price := EffectiveGasPrice(tx, header.BaseFee)
+	fee := new(big.Int).Mul(price, new(big.Int).SetUint64(receipt.GasUsed))
+	return &Receipt{Receipt: receipt, GasPrice: price, Fee: fee}, nil
+}
+
+func EffectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if baseFee == nil || tx.Type() == types.LegacyTxType {
+		return tx.GasPrice()
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if price.Cmp(tx.GasFeeCap()) > 0 {
+		return tx.GasFeeCap()
+	}
+	return price
+}
@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/qxoo/mywallet/units"
+)
+
+// PRICE_BUMP is the percentage nodes require a replacement to raise the gas
+// price, or both the max fee and the tip, by.
+const PRICE_BUMP = 10
+
+var ErrNotPending = errors.New("Transaction is not pending")
+
+// bumped is value raised by PRICE_BUMP percent, rounded up.
+func bumped(value *big.Int) *big.Int {
+	bump := new(big.Int).Mul(value, big.NewInt(100+PRICE_BUMP))
+	bump.Add(bump, big.NewInt(99))
+	return bump.Div(bump, big.NewInt(100))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if b != nil && b.Cmp(a) > 0 {
+		return new(big.Int).Set(b)
+	}
+	return a
+}
+
+// BumpFees returns fees for a replacement of old, the higher of the bumped
+// fees of old and the fees suggested now. Fees in opts below the bump are
+// refused, they would be rejected as underpriced.
+func BumpFees(old *types.Transaction, suggested *Fees, opts FeeOpts) (*Fees, error) {
+	if old.Type() == types.LegacyTxType {
+		least := bumped(old.GasPrice())
+		if opts.MaxFee != nil {
+			if opts.MaxFee.Cmp(least) < 0 {
+				return nil, fmt.Errorf("Gas price %s gwei is below the required %s gwei", units.FormatGwei(opts.MaxFee), units.FormatGwei(least))
+			}
+			return &Fees{GasPrice: opts.MaxFee}, nil
+		}
+		return &Fees{GasPrice: maxBig(least, suggested.MaxPrice())}, nil
+	}
+
+	leastTip, leastFee := bumped(old.GasTipCap()), bumped(old.GasFeeCap())
+	tip := maxBig(leastTip, suggested.GasTipCap)
+	if opts.Tip != nil {
+		if opts.Tip.Cmp(leastTip) < 0 {
+			return nil, fmt.Errorf("Tip %s gwei is below the required %s gwei", units.FormatGwei(opts.Tip), units.FormatGwei(leastTip))
+		}
+		tip = opts.Tip
+	}
+	maxfee := maxBig(leastFee, suggested.MaxPrice())
+	if opts.MaxFee != nil {
+		if opts.MaxFee.Cmp(leastFee) < 0 {
+			return nil, fmt.Errorf("Max fee %s gwei is below the required %s gwei", units.FormatGwei(opts.MaxFee), units.FormatGwei(leastFee))
+		}
+		if opts.MaxFee.Cmp(tip) < 0 {
+			return nil, fmt.Errorf("Max fee %s gwei is below the tip %s gwei", units.FormatGwei(opts.MaxFee), units.FormatGwei(tip))
+		}
+		maxfee = opts.MaxFee
+	}
+	maxfee = maxBig(maxfee, tip)
+	return &Fees{GasFeeCap: maxfee, GasTipCap: tip}, nil
+}
+
+// Replace sends a transaction with the nonce of the pending transaction
+// hash and bumped fees, the same transaction for a speedup or a zero value
+// transfer to the sender for a cancel.
+func (w *Wallet) Replace(hash string, cancel bool, opts FeeOpts) (old *types.Transaction, tx *types.Transaction, err error) {
+	if w.Client == nil {
+		return nil, nil, fmt.Errorf("Please Init EthClient")
+	}
+
+	old, pending, err := w.Client.TransactionByHash(context.Background(), common.HexToHash(hash))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Query Transaction %s error: %w", hash, Classify(err))
+	}
+	if !pending {
+		return old, nil, fmt.Errorf("%w: %s is already mined", ErrNotPending, hash)
+	}
+	sender, err := TxSender(old)
+	if err != nil {
+		return old, nil, err
+	}
+	if sender != w.Account.Address {
+		return old, nil, fmt.Errorf("Transaction %s is sent by %s", hash, sender.Hex())
+	}
+
+	chainid, err := ChainID(w.Client)
+	if err != nil {
+		return old, nil, err
+	}
+	suggested, err := SuggestFees(w.Client, FeeOpts{})
+	if err != nil {
+		return old, nil, Classify(err)
+	}
+	fees, err := BumpFees(old, suggested, opts)
+	if err != nil {
+		return old, nil, err
+	}
+
+	to, amount, gas, data := old.To(), old.Value(), old.Gas(), old.Data()
+	if cancel {
+		to, amount, gas, data = &w.Account.Address, big.NewInt(0), params.TxGas, nil
+	}
+	unsigned := fees.NewTx(chainid, old.Nonce(), to, amount, gas, data)
+	tx, err = w.SignTx(unsigned, chainid)
+	if err != nil {
+		return old, nil, err
+	}
+	if err := w.Client.SendTransaction(context.Background(), tx); err != nil {
+		return old, nil, Classify(err)
+	}
+	return old, tx, nil
+}
@@ -0,0 +1,78 @@
+package wallet
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestBumped(t *testing.T) {
+	tests := []struct {
+		value, want int64
+	}{
+		{value: 0, want: 0},
+		{value: 1, want: 2},
+		{value: 10, want: 11},
+		{value: 100, want: 110},
+		{value: 101, want: 112},
+		{value: 1000000001, want: 1100000002},
+	}
+	for _, tt := range tests {
+		if got := bumped(big.NewInt(tt.value)); got.Int64() != tt.want {
+			t.Errorf("bumped(%d) = %s, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBumpFees(t *testing.T) {
+	legacy := types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(1000)})
+	dynamic := types.NewTx(&types.DynamicFeeTx{GasFeeCap: big.NewInt(1000), GasTipCap: big.NewInt(101)})
+	low := &Fees{GasFeeCap: big.NewInt(1), GasTipCap: big.NewInt(1)}
+
+	tests := []struct {
+		name      string
+		old       *types.Transaction
+		suggested *Fees
+		opts      FeeOpts
+		price     int64
+		maxfee    int64
+		tip       int64
+		fail      string
+	}{
+		{name: "legacy bump", old: legacy, suggested: &Fees{GasPrice: big.NewInt(500)}, price: 1100},
+		{name: "legacy suggested", old: legacy, suggested: &Fees{GasPrice: big.NewInt(2000)}, price: 2000},
+		{name: "legacy explicit", old: legacy, suggested: low, opts: FeeOpts{MaxFee: big.NewInt(1100)}, price: 1100},
+		{name: "legacy explicit low", old: legacy, suggested: low, opts: FeeOpts{MaxFee: big.NewInt(1099)}, fail: "Gas price"},
+		{name: "dynamic bump", old: dynamic, suggested: low, maxfee: 1100, tip: 112},
+		{name: "dynamic suggested", old: dynamic, suggested: &Fees{GasFeeCap: big.NewInt(3000), GasTipCap: big.NewInt(200)}, maxfee: 3000, tip: 200},
+		{name: "dynamic explicit", old: dynamic, suggested: low, opts: FeeOpts{MaxFee: big.NewInt(1500), Tip: big.NewInt(120)}, maxfee: 1500, tip: 120},
+		{name: "dynamic tip low", old: dynamic, suggested: low, opts: FeeOpts{Tip: big.NewInt(111)}, fail: "Tip"},
+		{name: "dynamic fee low", old: dynamic, suggested: low, opts: FeeOpts{MaxFee: big.NewInt(1099)}, fail: "Max fee"},
+		{name: "dynamic tip raises fee", old: dynamic, suggested: low, opts: FeeOpts{Tip: big.NewInt(1200)}, maxfee: 1200, tip: 1200},
+		{name: "dynamic fee below tip", old: dynamic, suggested: low, opts: FeeOpts{MaxFee: big.NewInt(1100), Tip: big.NewInt(1200)}, fail: "below the tip"},
+	}
+	for _, tt := range tests {
+		fees, err := BumpFees(tt.old, tt.suggested, tt.opts)
+		if tt.fail != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.fail) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.fail)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if tt.price != 0 {
+			if fees.Dynamic() || fees.GasPrice.Int64() != tt.price {
+				t.Errorf("%s: fees %s, want gas price %d", tt.name, fees, tt.price)
+			}
+			continue
+		}
+		if !fees.Dynamic() || fees.GasFeeCap.Int64() != tt.maxfee || fees.GasTipCap.Int64() != tt.tip {
+			t.Errorf("%s: fees %s, want max fee %d tip %d", tt.name, fees, tt.maxfee, tt.tip)
+		}
+	}
+}
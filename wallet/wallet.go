@@ -4,18 +4,15 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"fmt"
-	"math"
 	"math/big"
 
-	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/qxoo/mywallet/config"
 	"github.com/tyler-smith/go-bip39"
 )
 
@@ -44,51 +41,31 @@ func DerivePrivateKey(path accounts.DerivationPath, masterKey *hdkeychain.Extend
 	return privateKeyEcdsa, nil
 }
 
-func PrivateFromWords(words string) (*ecdsa.PrivateKey, *common.Address, error) {
-	path, err := accounts.ParseDerivationPath("m/44'/60'/0'/0/0")
-	if err != nil {
-		return nil, nil, err
-	}
-
-	seed, err := bip39.NewSeedWithErrorChecking(words, "")
-	if err != nil {
-		return nil, nil, err
-	}
-	masterKey, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
-	if err != nil {
-		return nil, nil, err
-	}
-	privateKey, err := DerivePrivateKey(path, masterKey)
-	if err != nil {
-		return nil, nil, err
-	}
-	_publicKey := privateKey.Public()
-	publicKey, ok := _publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, nil, fmt.Errorf("public error")
-	}
-	address := crypto.PubkeyToAddress(*publicKey)
-	return privateKey, &address, nil
+func PrivateFromWords(words string, passphrase string) (*ecdsa.PrivateKey, *common.Address, error) {
+	return PrivateFromPath(words, passphrase, accounts.DefaultBaseDerivationPath)
 }
 
 type Wallet struct {
-	Account  accounts.Account
-	KeyStore *keystore.KeyStore
-	Pass     string
-	Words    string
-	Client   *ethclient.Client
+	Account    accounts.Account
+	KeyStore   *keystore.KeyStore
+	Pass       string
+	Words      string
+	Passphrase string
+	Path       accounts.DerivationPath
+	Client     *ethclient.Client
+	Nonces     *NonceManager
 }
 
-func NewWallet(path string, pass string) (*Wallet, error) {
+func NewWallet(path string, pass string, passphrase string, hdpath accounts.DerivationPath) (*Wallet, error) {
 	words, err := CreateWords()
 	if err != nil {
 		return nil, err
 	}
-	privatekey, _, err := PrivateFromWords(words)
+	privatekey, _, err := PrivateFromPath(words, passphrase, hdpath)
 	if err != nil {
 		return nil, err
 	}
-	wallet := &Wallet{Words: words}
+	wallet := &Wallet{Words: words, Passphrase: passphrase, Path: hdpath}
 	if err := wallet.Store(path, pass, privatekey); err != nil {
 		return nil, err
 	}
@@ -96,12 +73,12 @@ func NewWallet(path string, pass string) (*Wallet, error) {
 	return wallet, nil
 }
 
-func ImportWallet(words string, path string, pass string) (*Wallet, error) {
-	privatekey, _, err := PrivateFromWords(words)
+func ImportWallet(words string, path string, pass string, passphrase string, hdpath accounts.DerivationPath) (*Wallet, error) {
+	privatekey, _, err := PrivateFromPath(words, passphrase, hdpath)
 	if err != nil {
 		return nil, err
 	}
-	wallet := &Wallet{Words: words}
+	wallet := &Wallet{Words: words, Passphrase: passphrase, Path: hdpath}
 	if err := wallet.Store(path, pass, privatekey); err != nil {
 		return nil, err
 	}
@@ -122,7 +99,7 @@ func LoadWallet(path, pass string, address string) (*Wallet, error) {
 	ks := keystore.NewKeyStore(path, keystore.LightScryptN, keystore.LightScryptP)
 	_address := common.HexToAddress(address)
 	if !ks.HasAddress(_address) {
-		return nil, fmt.Errorf("Address %s not exists", address)
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, address)
 	}
 	account := accounts.Account{Address: _address}
 	signAccount, err := ks.Find(account)
@@ -139,13 +116,13 @@ func DeleteWallet(path, pass string, address string) error {
 		return nil
 	}
 	account := accounts.Account{Address: _address}
-	return ks.Delete(account, pass)
+	return Classify(ks.Delete(account, pass))
 }
 
 func (w *Wallet) InitEthClient(url string) error {
 	client, err := ethclient.Dial(url)
 	if err != nil {
-		return err
+		return Classify(err)
 	}
 	w.Client = client
 	return nil
@@ -162,61 +139,99 @@ func (w *Wallet) Store(path string, pass string, privatekey *ecdsa.PrivateKey) e
 	return nil
 }
 
-func (w *Wallet) Transfer(toaddr string, value float64) error {
-	if w.Client == nil {
-		return fmt.Errorf("Please Init EthClient")
-	}
+// Prepare asks the node for the nonce, fees, gas and chain id of a
+// transaction from the address from.
+func Prepare(client *ethclient.Client, from common.Address, toaddr string, amount *big.Int, data []byte, opts FeeOpts) (*TxParams, error) {
+	to_addr := common.HexToAddress(toaddr)
 
-	if err := w.KeyStore.Unlock(w.Account, w.Pass); err != nil {
-		return err
+	nonce, err := client.PendingNonceAt(context.Background(), from)
+	if err != nil {
+		return nil, Classify(err)
 	}
 
-	to_addr := common.HexToAddress(toaddr)
+	fees, err := SuggestFees(client, opts)
+	if err != nil {
+		return nil, Classify(err)
+	}
 
-	nonce, err := w.Client.PendingNonceAt(context.Background(), w.Account.Address)
+	gas, err := EstimateGas(client, ethereum.CallMsg{
+		From:      from,
+		To:        &to_addr,
+		GasPrice:  fees.GasPrice,
+		GasFeeCap: fees.GasFeeCap,
+		GasTipCap: fees.GasTipCap,
+		Value:     amount,
+		Data:      data,
+	})
 	if err != nil {
-		return err
+		return nil, Classify(err)
 	}
 
-	gasprice, err := w.Client.SuggestGasPrice(context.Background())
+	chainid, err := ChainID(client)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return &TxParams{Nonce: nonce, Gas: gas, ChainID: chainid, Fees: *fees}, nil
+}
+
+func (w *Wallet) Transfer(toaddr string, amount *big.Int, data []byte, opts FeeOpts) (*types.Transaction, error) {
+	if w.Client == nil {
+		return nil, fmt.Errorf("Please Init EthClient")
+	}
+
+	if err := w.KeyStore.Unlock(w.Account, w.Pass); err != nil {
+		return nil, Classify(err)
 	}
 
-	amount := big.NewInt(int64(value * math.Pow10(18)))
-	data := []byte("Transfer")
+	txparams, err := Prepare(w.Client, w.Account.Address, toaddr, amount, data, opts)
+	if err != nil {
+		return nil, err
+	}
 
-	tx := types.NewTransaction(nonce, to_addr, amount, config.Config.GasLimit, gasprice, data)
+	if nonce, err := w.ReserveNonce(); err != nil {
+		return nil, err
+	} else if nonce != nil {
+		txparams.Nonce = nonce.Uint64()
+	}
 
-	chainid, err := w.Client.NetworkID(context.Background())
+	signedTx, err := w.Sign(toaddr, amount, data, *txparams)
+	if err == nil {
+		err = Classify(w.Client.SendTransaction(context.Background(), signedTx))
+	}
 	if err != nil {
-		return err
+		w.ReleaseNonce(txparams.Nonce)
+		return nil, err
 	}
+	return signedTx, nil
+}
 
-	signedTx, err := w.KeyStore.SignTx(w.Account, tx, chainid)
+// ReserveNonce returns the nonce for the next transaction, from the nonce
+// manager if the wallet has one, nil lets the node fill in its pending nonce.
+func (w *Wallet) ReserveNonce() (*big.Int, error) {
+	if w.Nonces == nil {
+		return nil, nil
+	}
+	nonce, err := w.Nonces.Reserve(w.Client, w.Account.Address)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return new(big.Int).SetUint64(nonce), nil
+}
 
-	return w.Client.SendTransaction(context.Background(), signedTx)
+// ReleaseNonce gives back a nonce reserved for a transaction that was not sent.
+func (w *Wallet) ReleaseNonce(nonce uint64) {
+	if w.Nonces != nil {
+		w.Nonces.Release(w.Account.Address, nonce)
+	}
 }
 
-func (w Wallet) GetBalance() (float64, error) {
+func (w Wallet) GetBalance() (*big.Int, error) {
 	if w.Client == nil {
-		return 0, fmt.Errorf("Please Init EthClient")
+		return nil, fmt.Errorf("Please Init EthClient")
 	}
 
 	balance, err := w.Client.BalanceAt(context.Background(), w.Account.Address, nil)
-	if err != nil {
-		return 0, err
-	}
-
-	tmp := new(big.Float)
-	tmp.SetString(balance.String())
-
-	_balance := new(big.Float).Quo(tmp, big.NewFloat(math.Pow10(18)))
-	val, _ := _balance.Float64()
-	return val, nil
+	return balance, Classify(err)
 }
 
 func (w *Wallet) CloseClient() {